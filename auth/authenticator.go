@@ -4,11 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/tebben/toon-go-sdk/telemetry"
 )
 
 // Authentication server settings
@@ -30,9 +31,13 @@ type ToonAuthenticator struct {
 	callbackPort     int
 	IsAuthenticating bool
 	Token            OAuthToken
-	Events           chan AuthenticationEvent
+	// Events receives the authentication events, events are dropped when they are not read
+	Events chan AuthenticationEvent
 }
 
+// eventBufferSize is the number of authentication events kept for a reader of Events
+const eventBufferSize = 8
+
 // NewToonAuthenticator create a new Toon Authenticator
 func NewToonAuthenticator(clientID, clientSecret, tenantID, redirectURI, callbackHost, callbackEndpoint string, callbackPort int) *ToonAuthenticator {
 	ta := &ToonAuthenticator{
@@ -43,12 +48,17 @@ func NewToonAuthenticator(clientID, clientSecret, tenantID, redirectURI, callbac
 		callbackHost:     callbackHost,
 		callbackEndpoint: callbackEndpoint,
 		callbackPort:     callbackPort,
-		Events:           make(chan AuthenticationEvent),
+		Events:           make(chan AuthenticationEvent, eventBufferSize),
 	}
 
 	return ta
 }
 
+// TenantID returns the provider the authenticator logs in to, e.g. eneco or viesgo
+func (auth *ToonAuthenticator) TenantID() string {
+	return auth.tenantID
+}
+
 // StartGetToken description
 func (auth *ToonAuthenticator) StartGetToken(username, password string) {
 	auth.IsAuthenticating = true
@@ -62,9 +72,11 @@ func (auth *ToonAuthenticator) StartGetToken(username, password string) {
 // StartRefreshToken description
 func (auth *ToonAuthenticator) StartRefreshToken() {
 	auth.IsAuthenticating = true
-	auth.Events <- TokenRefreshing
+	auth.emit(TokenRefreshing)
+	telemetry.Logger().Info("refreshing toon access token", "client_id", auth.clientID)
 
 	go func() {
+		start := time.Now()
 		resp, err := postFormData(TokenURL,
 			FormValue{key: "client_id", value: auth.clientID},
 			FormValue{key: "client_secret", value: auth.clientSecret},
@@ -72,12 +84,15 @@ func (auth *ToonAuthenticator) StartRefreshToken() {
 			FormValue{key: "refresh_token", value: auth.Token.RefreshToken},
 		)
 
-		auth.parseAndSetToken(err, resp)
+		auth.parseAndSetToken(telemetry.AuthStageRefresh, start, err, resp)
 	}()
 }
 
 // login sends form data to the login page, no need for user interaction this way, callback will be triggered
 func (auth *ToonAuthenticator) login(username, password string) {
+	telemetry.Logger().Info("logging in to toon", "tenant_id", auth.tenantID)
+
+	start := time.Now()
 	resp, err := postFormData(AuthURL,
 		FormValue{key: "client_id", value: auth.clientID},
 		FormValue{key: "username", value: username},
@@ -89,14 +104,27 @@ func (auth *ToonAuthenticator) login(username, password string) {
 		FormValue{key: "scope", value: ""},
 	)
 
-	if err != nil || resp.StatusCode != 200 {
-		auth.TokenError = fmt.Sprintf("Unable to get OAuth access token, login failed: %v", err)
-		auth.Events <- TokenError
+	event := telemetry.AuthEvent{Stage: telemetry.AuthStageLogin, Duration: time.Since(start), Err: err}
+	if resp != nil {
+		resp.Body.Close()
+		event.StatusCode = resp.StatusCode
+	}
+
+	if err == nil && resp.StatusCode != 200 {
+		event.Err = fmt.Errorf("login returned status %v", resp.StatusCode)
+	}
+
+	telemetry.ActiveTracer().Auth(event)
+	if event.Err != nil {
+		telemetry.Logger().Error("toon login failed", "status", event.StatusCode, "error", event.Err)
+		auth.TokenError = fmt.Sprintf("Unable to get OAuth access token, login failed: %v", event.Err)
+		auth.emit(TokenError)
 	}
 }
 
 // request the OAuth service for a new token
 func (auth *ToonAuthenticator) getToken(code string) {
+	start := time.Now()
 	resp, err := postFormData(TokenURL,
 		FormValue{key: "client_id", value: auth.clientID},
 		FormValue{key: "client_secret", value: auth.clientSecret},
@@ -104,26 +132,52 @@ func (auth *ToonAuthenticator) getToken(code string) {
 		FormValue{key: "code", value: code},
 	)
 
-	auth.parseAndSetToken(err, resp)
+	auth.parseAndSetToken(telemetry.AuthStageCodeExchange, start, err, resp)
 }
 
-func (auth *ToonAuthenticator) parseAndSetToken(err error, resp *http.Response) {
-	if err != nil || resp.StatusCode != 200 {
-		auth.TokenError = fmt.Sprintf("%v", err)
-		auth.Events <- TokenError
+func (auth *ToonAuthenticator) parseAndSetToken(stage telemetry.AuthStage, start time.Time, err error, resp *http.Response) {
+	event := telemetry.AuthEvent{Stage: stage, Err: err}
+	if err == nil {
+		defer resp.Body.Close()
+		event.StatusCode = resp.StatusCode
+		if resp.StatusCode != 200 {
+			event.Err = fmt.Errorf("token endpoint returned status %v", resp.StatusCode)
+		}
 	}
 
 	token := OAuthToken{}
-	body, _ := ioutil.ReadAll(resp.Body)
-	err = json.Unmarshal(body, &token)
-	if err != nil {
-		auth.TokenError = fmt.Sprintf("Unable to parse received OAuth token: %v", err)
-		auth.Events <- TokenError
+	if event.Err == nil {
+		body, _ := ioutil.ReadAll(resp.Body)
+		err = json.Unmarshal(body, &token)
+		if err != nil {
+			event.Err = fmt.Errorf("Unable to parse received OAuth token: %v", err)
+		}
+	}
+
+	event.Duration = time.Since(start)
+	telemetry.ActiveTracer().Auth(event)
+	if event.Err != nil {
+		telemetry.Logger().Error("unable to get toon access token", "stage", stage, "status", event.StatusCode, "error", event.Err)
+		auth.IsAuthenticating = false
+		auth.TokenError = fmt.Sprintf("%v", event.Err)
+		auth.emit(TokenError)
+		return
 	}
 
+	telemetry.Logger().Info("received toon access token", "stage", stage, "expires_in", token.ExpiresIn)
 	auth.IsAuthenticating = false
 	auth.Token = token
-	auth.Events <- TokenReceived
+	auth.emit(TokenReceived)
+}
+
+// emit sends an authentication event without blocking, the event is dropped when nobody
+// reads Events and the buffer is full so API calls never wait for a reader
+func (auth *ToonAuthenticator) emit(event AuthenticationEvent) {
+	select {
+	case auth.Events <- event:
+	default:
+		telemetry.Logger().Debug("toon authentication event dropped", "event", event)
+	}
 }
 
 // start a HTTP server with a handler to retrieve the OAuth code after login in
@@ -133,9 +187,13 @@ func (auth *ToonAuthenticator) startCallbackServer() {
 		callbackHandler(w, r, auth)
 	})
 
+	telemetry.Logger().Info("starting toon oauth callback server", "host", host, "endpoint", auth.callbackEndpoint)
 	err := http.ListenAndServe(host, nil)
 	if err != nil {
-		log.Fatal(err)
+		telemetry.Logger().Error("toon oauth callback server stopped", "host", host, "error", err)
+		auth.IsAuthenticating = false
+		auth.TokenError = fmt.Sprintf("Unable to start OAuth callback server: %v", err)
+		auth.emit(TokenError)
 	}
 }
 
@@ -143,9 +201,11 @@ func (auth *ToonAuthenticator) startCallbackServer() {
 // is used to get an access token
 func callbackHandler(w http.ResponseWriter, r *http.Request, auth *ToonAuthenticator) {
 	code := r.FormValue("code")
+	telemetry.ActiveTracer().CallbackReceived(telemetry.CallbackInfo{RemoteAddr: r.RemoteAddr, Path: r.URL.Path, HasCode: len(code) != 0})
+	telemetry.Logger().Debug("received toon oauth callback", "remote_addr", r.RemoteAddr, "code", telemetry.Secret(code))
 	if len(code) == 0 {
 		auth.TokenError = "No code found in OAuth2 callback"
-		auth.Events <- TokenError
+		auth.emit(TokenError)
 		return
 	}
 
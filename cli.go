@@ -35,6 +35,15 @@ var commands = []string{
 	"GetElectricityFlowData",
 	"GetElectricityGraphData",
 	"GetDistrictHeatGraphData",
+	"GetElectricityProductionFlowData",
+	"GetElectricityProductionGraphData",
+	"GetElectricityProductionAndDelivery",
+	"GetThermostatStates",
+	"GetThermostatInfo",
+	"GetCurrentTemperature",
+	"GetThermostatProgram",
+	"GetDevices",
+	"GetWebhooks",
 }
 
 func main() {
@@ -166,6 +175,62 @@ func fireCommand() {
 			printResponse(data, err)
 			break
 		}
+	case "getelectricityproductionflowdata":
+		{
+			data, err := toon.GetElectricityProductionFlowData(authenticator, ag[0].AgreementID, *startPtr, *endPtr)
+			printResponse(data, err)
+			break
+		}
+	case "getelectricityproductiongraphdata":
+		{
+			interval, _ := stringToInterval(*intervalPtr)
+			data, err := toon.GetElectricityProductionGraphData(authenticator, ag[0].AgreementID, *startPtr, *endPtr, interval)
+			printResponse(data, err)
+			break
+		}
+	case "getelectricityproductionanddelivery":
+		{
+			interval, _ := stringToInterval(*intervalPtr)
+			data, err := toon.GetElectricityProductionAndDelivery(authenticator, ag[0].AgreementID, *startPtr, *endPtr, interval)
+			printResponse(data, err)
+			break
+		}
+	case "getthermostatstates":
+		{
+			data, err := toon.GetThermostatStates(authenticator, ag[0].AgreementID)
+			printResponse(data, err)
+			break
+		}
+	case "getthermostatinfo":
+		{
+			data, err := toon.GetThermostatInfo(authenticator, ag[0].AgreementID)
+			printResponse(data, err)
+			break
+		}
+	case "getcurrenttemperature":
+		{
+			data, err := toon.GetCurrentTemperature(authenticator, ag[0].AgreementID)
+			printResponse(data, err)
+			break
+		}
+	case "getthermostatprogram":
+		{
+			data, err := toon.GetThermostatProgram(authenticator, ag[0].AgreementID)
+			printResponse(data, err)
+			break
+		}
+	case "getdevices":
+		{
+			data, err := toon.GetDevices(authenticator, ag[0].AgreementID)
+			printResponse(data, err)
+			break
+		}
+	case "getwebhooks":
+		{
+			data, err := toon.GetWebhooks(authenticator, ag[0].AgreementID)
+			printResponse(data, err)
+			break
+		}
 	}
 }
 
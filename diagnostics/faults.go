@@ -0,0 +1,126 @@
+// Package diagnostics interprets the boiler information reported by the Toon thermostat and tracks
+// it over successive GetStatus polls, raising and clearing faults which can be routed to on-call tooling.
+package diagnostics
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tebben/toon-go-sdk/toon"
+)
+
+// Severity of a fault
+type Severity int
+
+// Fault severities
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+var severities = [...]string{
+	"info",
+	"warning",
+	"critical",
+}
+
+// String() function will return the name of a severity
+func (s Severity) String() string {
+	return severities[s]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Fault codes raised by Interpret and the Monitor
+const (
+	FaultServiceRequest     = "service_request"
+	FaultLockout            = "lockout"
+	FaultLowWaterPressure   = "low_water_pressure"
+	FaultGasFlame           = "gas_flame"
+	FaultAirPressure        = "air_pressure"
+	FaultWaterOverTemp      = "water_over_temperature"
+	FaultUnknownBoilerError = "unknown_boiler_error"
+	FaultOpenThermComm      = "opentherm_communication"
+	FaultBoilerModule       = "boiler_module_disconnected"
+	FaultShortCycling       = "short_cycling"
+	FaultFullLoad           = "full_load"
+)
+
+// Fault is a human readable boiler problem
+type Fault struct {
+	Code        string   `json:"code"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+	// Raw is the raw value reported by the thermostat
+	Raw string `json:"raw"`
+}
+
+// openThermFlag is a bit of the OpenTherm application specific fault flags
+type openThermFlag struct {
+	bit         int
+	code        string
+	severity    Severity
+	description string
+}
+
+// ThermostatInfo.ErrorFound is toon.NoErrorFound (255) when the boiler reports no error, any other value is
+// the low byte of the OpenTherm application specific fault flags (data id 5). Only bits 0 to 5 are defined
+// flags, bits 6 and 7 are reserved so a value with one of them set, such as the 255 sentinel, is not read as
+// a set of flags and is reported as unknown error with its raw value.
+const openThermFlagMask = 0x3f
+
+// openThermFlags are the defined OpenTherm application specific fault flags, see openThermFlagMask
+var openThermFlags = []openThermFlag{
+	{0, FaultServiceRequest, SeverityWarning, "Boiler requests service"},
+	{1, FaultLockout, SeverityCritical, "Boiler is in lockout and needs a manual reset"},
+	{2, FaultLowWaterPressure, SeverityCritical, "Water pressure of the heating system is too low"},
+	{3, FaultGasFlame, SeverityCritical, "Gas supply or flame fault"},
+	{4, FaultAirPressure, SeverityCritical, "Air pressure fault"},
+	{5, FaultWaterOverTemp, SeverityCritical, "Water temperature too high"},
+}
+
+// Interpret returns the faults reported in the thermostat information, ordered by severity
+func Interpret(info toon.ThermostatInfo) []Fault {
+	faults := []Fault{}
+	if info.HasError() {
+		raw := fmt.Sprintf("%v", info.ErrorFound)
+		known := false
+		for _, flag := range openThermFlags {
+			if info.ErrorFound&^openThermFlagMask == 0 && info.ErrorFound&(1<<flag.bit) != 0 {
+				faults = append(faults, Fault{Code: flag.code, Severity: flag.severity, Description: flag.description, Raw: raw})
+				known = true
+			}
+		}
+
+		if !known {
+			faults = append(faults, Fault{Code: FaultUnknownBoilerError, Severity: SeverityWarning, Description: fmt.Sprintf("Boiler reports unknown error %v", info.ErrorFound), Raw: raw})
+		}
+	}
+
+	if info.HasOpenThermBoiler() && info.HasOpenThermCommError() {
+		faults = append(faults, Fault{Code: FaultOpenThermComm, Severity: SeverityCritical, Description: "No OpenTherm communication between thermostat and boiler", Raw: info.OtCommError})
+	}
+
+	// only an OpenTherm boiler is connected using the boiler module
+	if info.HasOpenThermBoiler() && !info.IsBoilerModuleConnected() {
+		faults = append(faults, Fault{Code: FaultBoilerModule, Severity: SeverityCritical, Description: "Boiler module is not connected to the thermostat", Raw: fmt.Sprintf("%v", info.BoilerModuleConnected)})
+	}
+
+	sortFaults(faults)
+	return faults
+}
+
+// sortFaults orders faults by severity, most severe first, and code
+func sortFaults(faults []Fault) {
+	sort.SliceStable(faults, func(i, j int) bool {
+		if faults[i].Severity != faults[j].Severity {
+			return faults[i].Severity > faults[j].Severity
+		}
+
+		return faults[i].Code < faults[j].Code
+	})
+}
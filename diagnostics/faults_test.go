@@ -0,0 +1,65 @@
+package diagnostics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tebben/toon-go-sdk/toon"
+)
+
+func TestInterpret(t *testing.T) {
+	tests := []struct {
+		name string
+		info toon.ThermostatInfo
+		want []string
+	}{
+		{
+			name: "no error",
+			info: toon.ThermostatInfo{ErrorFound: toon.NoErrorFound, HaveOTBoiler: 1, BoilerModuleConnected: 1},
+			want: []string{},
+		},
+		{
+			name: "single flag",
+			info: toon.ThermostatInfo{ErrorFound: 1 << 0, HaveOTBoiler: 1, BoilerModuleConnected: 1},
+			want: []string{FaultServiceRequest},
+		},
+		{
+			name: "multiple flags ordered by severity",
+			info: toon.ThermostatInfo{ErrorFound: 1<<0 | 1<<1 | 1<<2, HaveOTBoiler: 1, BoilerModuleConnected: 1},
+			want: []string{FaultLockout, FaultLowWaterPressure, FaultServiceRequest},
+		},
+		{
+			name: "reserved bits are not read as flags",
+			info: toon.ThermostatInfo{ErrorFound: 1<<6 | 1<<1, HaveOTBoiler: 1, BoilerModuleConnected: 1},
+			want: []string{FaultUnknownBoilerError},
+		},
+		{
+			name: "no flags set",
+			info: toon.ThermostatInfo{ErrorFound: 0, HaveOTBoiler: 1, BoilerModuleConnected: 1},
+			want: []string{FaultUnknownBoilerError},
+		},
+		{
+			name: "opentherm communication and boiler module",
+			info: toon.ThermostatInfo{ErrorFound: toon.NoErrorFound, HaveOTBoiler: 1, OtCommError: "1"},
+			want: []string{FaultBoilerModule, FaultOpenThermComm},
+		},
+		{
+			name: "no boiler module without opentherm boiler",
+			info: toon.ThermostatInfo{ErrorFound: toon.NoErrorFound, HaveOTBoiler: 0, BoilerModuleConnected: 0, OtCommError: "1"},
+			want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, fault := range Interpret(test.info) {
+				got = append(got, fault.Code)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("Interpret() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
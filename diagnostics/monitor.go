@@ -0,0 +1,266 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/toon"
+)
+
+// EventType is the type of a diagnostics Event
+type EventType int
+
+// Event types
+const (
+	FaultRaised EventType = iota
+	FaultCleared
+)
+
+var eventTypes = [...]string{
+	"fault raised",
+	"fault cleared",
+}
+
+// String() function will return the name of an event type
+func (t EventType) String() string {
+	return eventTypes[t]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// Event is emitted when a fault is raised or cleared
+type Event struct {
+	Type  EventType `json:"type"`
+	Fault Fault     `json:"fault"`
+	Time  time.Time `json:"time"`
+}
+
+// Sample is the boiler state of one GetStatus poll
+type Sample struct {
+	Time       time.Time        `json:"time"`
+	Burner     toon.BurnerState `json:"burner"`
+	Modulation int              `json:"modulation"`
+	Setpoint   float64          `json:"setpoint"`
+	Room       float64          `json:"room"`
+}
+
+// Default Monitor settings, used when a setting is zero
+const (
+	DefaultHistory          = time.Hour
+	DefaultShortCycleStarts = 6
+	DefaultShortCycleWindow = time.Hour
+	DefaultFullLoadWindow   = time.Hour
+)
+
+// fullModulation is the modulation level in percent of a boiler running at full load
+const fullModulation = 100
+
+// Monitor tracks the boiler state of one thermostat over successive GetStatus polls
+// and emits an event when a fault is raised or cleared. The zero value is a Monitor
+// with default settings.
+type Monitor struct {
+	mu      sync.Mutex
+	active  map[string]Fault
+	samples []Sample
+	// History is the time samples are kept, default 1 hour. Samples are always kept
+	// for at least the ShortCycleWindow and FullLoadWindow.
+	History time.Duration
+	// ShortCycleStarts is the number of burner starts within ShortCycleWindow
+	// which raises a short cycling fault, default 6
+	ShortCycleStarts int
+	// ShortCycleWindow is the window in which burner starts are counted, default 1 hour
+	ShortCycleWindow time.Duration
+	// FullLoadWindow is the time the boiler can heat at full modulation without reaching
+	// the setpoint before a full load fault is raised, default 1 hour
+	FullLoadWindow time.Duration
+	// OnEvent is called for every emitted event
+	OnEvent func(event Event)
+}
+
+// NewMonitor creates a new Monitor with default settings
+func NewMonitor() *Monitor {
+	return &Monitor{
+		active:           map[string]Fault{},
+		History:          DefaultHistory,
+		ShortCycleStarts: DefaultShortCycleStarts,
+		ShortCycleWindow: DefaultShortCycleWindow,
+		FullLoadWindow:   DefaultFullLoadWindow,
+	}
+}
+
+// Observe processes a status and returns the events for faults which are raised or cleared
+// since the previous status, the server time of the status is used as time of the events
+func (m *Monitor) Observe(status *toon.Status) []Event {
+	now := time.Now()
+	if status.ServerTime != 0 {
+		now = time.UnixMilli(status.ServerTime)
+	}
+
+	info := status.ThermostatInfo
+
+	m.mu.Lock()
+	m.samples = append(m.samples, Sample{
+		Time:       now,
+		Burner:     info.Burner(),
+		Modulation: info.CurrentModulationLevel,
+		Setpoint:   info.SetpointCelsius(),
+		Room:       info.DisplayTempCelsius(),
+	})
+	m.prune(now)
+
+	faults := Interpret(info)
+	if starts := m.burnerStarts(now); starts >= m.shortCycleStarts() {
+		faults = append(faults, Fault{
+			Code:        FaultShortCycling,
+			Severity:    SeverityWarning,
+			Description: fmt.Sprintf("Burner started %v times in %v", starts, m.shortCycleWindow()),
+			Raw:         fmt.Sprintf("%v", starts),
+		})
+	}
+
+	if m.fullLoad(now) {
+		faults = append(faults, Fault{
+			Code:        FaultFullLoad,
+			Severity:    SeverityWarning,
+			Description: fmt.Sprintf("Boiler heated at full modulation for %v without reaching the setpoint", m.fullLoadWindow()),
+			Raw:         fmt.Sprintf("%v", info.CurrentModulationLevel),
+		})
+	}
+
+	current := map[string]Fault{}
+	events := []Event{}
+	for _, fault := range faults {
+		current[fault.Code] = fault
+		if _, ok := m.active[fault.Code]; !ok {
+			events = append(events, Event{Type: FaultRaised, Fault: fault, Time: now})
+		}
+	}
+
+	cleared := []Fault{}
+	for code, fault := range m.active {
+		if _, ok := current[code]; !ok {
+			cleared = append(cleared, fault)
+		}
+	}
+
+	sortFaults(cleared)
+	for _, fault := range cleared {
+		events = append(events, Event{Type: FaultCleared, Fault: fault, Time: now})
+	}
+
+	m.active = current
+	m.mu.Unlock()
+
+	if m.OnEvent != nil {
+		for _, event := range events {
+			m.OnEvent(event)
+		}
+	}
+
+	return events
+}
+
+// ActiveFaults returns the faults which are currently raised, ordered by severity
+func (m *Monitor) ActiveFaults() []Fault {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	faults := make([]Fault, 0, len(m.active))
+	for _, fault := range m.active {
+		faults = append(faults, fault)
+	}
+
+	sortFaults(faults)
+	return faults
+}
+
+// Samples returns the boiler state of the polls within the History
+func (m *Monitor) Samples() []Sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Sample(nil), m.samples...)
+}
+
+func (m *Monitor) history() time.Duration {
+	history := m.History
+	if history <= 0 {
+		history = DefaultHistory
+	}
+
+	return max(history, m.shortCycleWindow(), m.fullLoadWindow())
+}
+
+func (m *Monitor) shortCycleStarts() int {
+	if m.ShortCycleStarts <= 0 {
+		return DefaultShortCycleStarts
+	}
+
+	return m.ShortCycleStarts
+}
+
+func (m *Monitor) shortCycleWindow() time.Duration {
+	if m.ShortCycleWindow <= 0 {
+		return DefaultShortCycleWindow
+	}
+
+	return m.ShortCycleWindow
+}
+
+func (m *Monitor) fullLoadWindow() time.Duration {
+	if m.FullLoadWindow <= 0 {
+		return DefaultFullLoadWindow
+	}
+
+	return m.FullLoadWindow
+}
+
+// prune removes samples older than the History, should be called while holding the lock
+func (m *Monitor) prune(now time.Time) {
+	history := m.history()
+	i := 0
+	for i < len(m.samples) && now.Sub(m.samples[i].Time) > history {
+		i++
+	}
+
+	m.samples = m.samples[i:]
+}
+
+// burnerStarts counts the times the burner went from idle to burning within the ShortCycleWindow
+func (m *Monitor) burnerStarts(now time.Time) int {
+	window := m.shortCycleWindow()
+	starts := 0
+	for i := 1; i < len(m.samples); i++ {
+		if now.Sub(m.samples[i].Time) > window {
+			continue
+		}
+
+		if m.samples[i-1].Burner == toon.BurnerIdle && m.samples[i].Burner != toon.BurnerIdle && m.samples[i].Burner != toon.BurnerUnknown {
+			starts++
+		}
+	}
+
+	return starts
+}
+
+// fullLoad returns true when all samples of the last FullLoadWindow show the burner heating at full
+// modulation while the room temperature stays below the setpoint, the boiler can not keep up with
+// the heat demand which for instance happens with air in the system or a clogged heat exchanger
+func (m *Monitor) fullLoad(now time.Time) bool {
+	window := m.fullLoadWindow()
+	for i := len(m.samples) - 1; i >= 0; i-- {
+		sample := m.samples[i]
+		if sample.Burner != toon.BurnerCentralHeating || sample.Modulation < fullModulation || sample.Room >= sample.Setpoint {
+			return false
+		}
+
+		if now.Sub(sample.Time) >= window {
+			return true
+		}
+	}
+
+	return false
+}
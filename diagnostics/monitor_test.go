@@ -0,0 +1,147 @@
+package diagnostics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/toon"
+)
+
+var monitorStart = time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+
+// testStatus returns a healthy status at the given offset from monitorStart
+func testStatus(offset time.Duration, burner string) *toon.Status {
+	return &toon.Status{
+		ServerTime: monitorStart.Add(offset).UnixMilli(),
+		ThermostatInfo: toon.ThermostatInfo{
+			ErrorFound:            toon.NoErrorFound,
+			HaveOTBoiler:          1,
+			BoilerModuleConnected: 1,
+			BurnerInfo:            burner,
+			CurrentSetpoint:       2000,
+			CurrentDisplayTemp:    2000,
+		},
+	}
+}
+
+func eventCodes(events []Event) []string {
+	codes := []string{}
+	for _, event := range events {
+		codes = append(codes, event.Type.String()+" "+event.Fault.Code)
+	}
+
+	return codes
+}
+
+func TestMonitorRaiseAndClear(t *testing.T) {
+	m := NewMonitor()
+	emitted := []Event{}
+	m.OnEvent = func(event Event) { emitted = append(emitted, event) }
+
+	status := testStatus(0, "0")
+	status.ThermostatInfo.ErrorFound = 1 << 1
+	if got, want := eventCodes(m.Observe(status)), []string{"fault raised " + FaultLockout}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Observe() = %v, want %v", got, want)
+	}
+
+	// a fault which stays active is not raised again
+	status = testStatus(time.Minute, "0")
+	status.ThermostatInfo.ErrorFound = 1 << 1
+	if got := m.Observe(status); len(got) != 0 {
+		t.Fatalf("Observe() = %v, want no events", eventCodes(got))
+	}
+
+	if got := m.ActiveFaults(); len(got) != 1 || got[0].Code != FaultLockout {
+		t.Fatalf("ActiveFaults() = %v, want %v", got, FaultLockout)
+	}
+
+	events := m.Observe(testStatus(2*time.Minute, "0"))
+	if got, want := eventCodes(events), []string{"fault cleared " + FaultLockout}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Observe() = %v, want %v", got, want)
+	}
+
+	if !events[0].Time.Equal(monitorStart.Add(2 * time.Minute)) {
+		t.Fatalf("event time %v, want the server time of the status", events[0].Time)
+	}
+
+	if len(emitted) != 2 || len(m.ActiveFaults()) != 0 {
+		t.Fatalf("OnEvent called %d times with %d active faults, want 2 and 0", len(emitted), len(m.ActiveFaults()))
+	}
+}
+
+func TestMonitorShortCycling(t *testing.T) {
+	tests := []struct {
+		name   string
+		m      *Monitor
+		starts int
+		want   bool
+	}{
+		{name: "below the threshold", m: NewMonitor(), starts: 5, want: false},
+		{name: "at the threshold", m: NewMonitor(), starts: 6, want: true},
+		{name: "zero value uses the defaults", m: &Monitor{}, starts: 5, want: false},
+		{name: "custom threshold", m: &Monitor{ShortCycleStarts: 3}, starts: 3, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raised := false
+			offset := time.Duration(0)
+			for i := 0; i < test.starts; i++ {
+				for _, burner := range []string{"0", "1"} {
+					for _, event := range test.m.Observe(testStatus(offset, burner)) {
+						raised = raised || (event.Type == FaultRaised && event.Fault.Code == FaultShortCycling)
+					}
+
+					offset += 4 * time.Minute
+				}
+			}
+
+			if raised != test.want {
+				t.Fatalf("short cycling raised %v, want %v", raised, test.want)
+			}
+		})
+	}
+}
+
+func TestMonitorShortCyclingWindow(t *testing.T) {
+	m := &Monitor{History: time.Nanosecond}
+
+	// six starts spread over three hours are not short cycling, and a small History does not drop the window
+	for i := 0; i < 6; i++ {
+		offset := time.Duration(i) * 30 * time.Minute
+		m.Observe(testStatus(offset, "0"))
+		if events := m.Observe(testStatus(offset+time.Minute, "1")); len(events) != 0 {
+			t.Fatalf("start %d: Observe() = %v, want no events", i, eventCodes(events))
+		}
+	}
+
+	if got := len(m.Samples()); got < 4 {
+		t.Fatalf("%d samples kept, want the samples of the short cycling window", got)
+	}
+}
+
+func TestMonitorFullLoad(t *testing.T) {
+	m := NewMonitor()
+	status := func(offset time.Duration, modulation, room int) *toon.Status {
+		status := testStatus(offset, "1")
+		status.ThermostatInfo.CurrentModulationLevel = modulation
+		status.ThermostatInfo.CurrentDisplayTemp = room
+		return status
+	}
+
+	for offset := time.Duration(0); offset < time.Hour; offset += 5 * time.Minute {
+		if events := m.Observe(status(offset, 100, 1800)); len(events) != 0 {
+			t.Fatalf("Observe() = %v before the full load window passed", eventCodes(events))
+		}
+	}
+
+	if got, want := eventCodes(m.Observe(status(time.Hour, 100, 1800))), []string{"fault raised " + FaultFullLoad}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Observe() = %v, want %v", got, want)
+	}
+
+	// the boiler modulates down once the room warms up
+	if got, want := eventCodes(m.Observe(status(time.Hour+5*time.Minute, 40, 1950))), []string{"fault cleared " + FaultFullLoad}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Observe() = %v, want %v", got, want)
+	}
+}
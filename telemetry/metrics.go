@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Metric names recorded by the MetricsTracer
+const (
+	MetricAPIRequests        = "toon_api_requests_total"
+	MetricAPIRequestDuration = "toon_api_request_duration_seconds"
+	MetricAPIRetries         = "toon_api_retries_total"
+	MetricAPIRejections      = "toon_api_rejections_total"
+	MetricCacheLookups       = "toon_cache_lookups_total"
+	MetricTokenRefreshes     = "toon_token_refreshes_total"
+	MetricAuthErrors         = "toon_auth_errors_total"
+)
+
+// metricHelp contains the description of known metrics, rendered as HELP line
+var metricHelp = map[string]string{
+	MetricAPIRequests:        "Number of requests send to the Toon API by endpoint template and status code.",
+	MetricAPIRequestDuration: "Latency of requests send to the Toon API in seconds.",
+	MetricAPIRetries:         "Number of retried requests to the Toon API.",
+	MetricAPIRejections:      "Number of requests not send to the Toon API by the rate limiter or circuit breaker by reason.",
+	MetricCacheLookups:       "Number of cache lookups by cache and result.",
+	MetricTokenRefreshes:     "Number of OAuth token refreshes by result.",
+	MetricAuthErrors:         "Number of failed authentication steps by stage.",
+}
+
+// Labels are the label names and values of a metric
+type Labels map[string]string
+
+// Metrics is a small interface to record counters and histograms, implement it to
+// forward the SDK metrics to your own metrics library or use a Registry
+type Metrics interface {
+	// IncCounter increments the counter with the given name and labels by one
+	IncCounter(name string, labels Labels)
+	// ObserveHistogram adds a value to the histogram with the given name and labels
+	ObserveHistogram(name string, labels Labels, value float64)
+}
+
+// MetricsTracer is a Tracer that records the SDK hooks as metrics
+type MetricsTracer struct {
+	NopTracer
+	metrics Metrics
+}
+
+// NewMetricsTracer creates a Tracer recording to the given metrics, combine it
+// with other tracers using MultiTracer
+func NewMetricsTracer(metrics Metrics) *MetricsTracer {
+	return &MetricsTracer{metrics: metrics}
+}
+
+// RequestEnd records the request count and latency, a request which did not
+// receive a response is recorded with code "error"
+func (t *MetricsTracer) RequestEnd(info RequestInfo, result RequestResult) {
+	code := "error"
+	if result.StatusCode != 0 {
+		code = strconv.Itoa(result.StatusCode)
+	}
+
+	t.metrics.IncCounter(MetricAPIRequests, Labels{"endpoint": info.Endpoint, "method": info.Method, "code": code})
+	t.metrics.ObserveHistogram(MetricAPIRequestDuration, Labels{"endpoint": info.Endpoint, "method": info.Method}, result.Duration.Seconds())
+}
+
+// RequestRetry records a retry
+func (t *MetricsTracer) RequestRetry(info RequestInfo, attempt int, reason string) {
+	t.metrics.IncCounter(MetricAPIRetries, Labels{"endpoint": info.Endpoint, "method": info.Method})
+}
+
+// RequestRejected records a request which was not send
+func (t *MetricsTracer) RequestRejected(info RequestInfo, reason RejectReason, err error) {
+	t.metrics.IncCounter(MetricAPIRejections, Labels{"endpoint": info.Endpoint, "method": info.Method, "reason": string(reason)})
+}
+
+// CacheLookup records a cache hit or miss
+func (t *MetricsTracer) CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	t.metrics.IncCounter(MetricCacheLookups, Labels{"cache": cache, "result": result})
+}
+
+// Auth records token refreshes and authentication errors
+func (t *MetricsTracer) Auth(event AuthEvent) {
+	if event.Stage == AuthStageRefresh {
+		result := "success"
+		if event.Err != nil {
+			result = "error"
+		}
+
+		t.metrics.IncCounter(MetricTokenRefreshes, Labels{"result": result})
+	}
+
+	if event.Err != nil {
+		t.metrics.IncCounter(MetricAuthErrors, Labels{"stage": string(event.Stage)})
+	}
+}
+
+// errInvalidMetricName is returned when a metric or label name can not be rendered
+var errInvalidMetricName = errors.New("invalid metric name")
+
+// validateName checks if a metric or label name is valid in the Prometheus data model
+func validateName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("%w: empty name", errInvalidMetricName)
+	}
+
+	for i, c := range name {
+		if c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+
+		return fmt.Errorf("%w: %q", errInvalidMetricName, name)
+	}
+
+	return nil
+}
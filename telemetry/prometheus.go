@@ -0,0 +1,208 @@
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets in seconds used by NewRegistry
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry is an in-memory implementation of Metrics which can render
+// all recorded metrics in the Prometheus text exposition format
+type Registry struct {
+	mu         sync.Mutex
+	buckets    []float64
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewRegistry creates a Registry using the DefaultBuckets for histograms
+func NewRegistry() *Registry {
+	return NewRegistryWithBuckets(DefaultBuckets)
+}
+
+// NewRegistryWithBuckets creates a Registry with custom histogram buckets
+func NewRegistryWithBuckets(buckets []float64) *Registry {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+
+	return &Registry{
+		buckets:    b,
+		counters:   map[string]map[string]float64{},
+		histograms: map[string]map[string]*histogram{},
+	}
+}
+
+// IncCounter implements Metrics
+func (r *Registry) IncCounter(name string, labels Labels) {
+	key := renderLabels(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counters[name] == nil {
+		r.counters[name] = map[string]float64{}
+	}
+
+	r.counters[name][key]++
+}
+
+// ObserveHistogram implements Metrics
+func (r *Registry) ObserveHistogram(name string, labels Labels, value float64) {
+	key := renderLabels(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.histograms[name] == nil {
+		r.histograms[name] = map[string]*histogram{}
+	}
+
+	h := r.histograms[name][key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(r.buckets))}
+		r.histograms[name][key] = h
+	}
+
+	for i, b := range r.buckets {
+		if value <= b {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// Counter returns the current value of a counter, mainly useful to check quotas
+func (r *Registry) Counter(name string, labels Labels) float64 {
+	key := renderLabels(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[name][key]
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, name := range sortedKeys(r.counters) {
+		if err := validateName(name); err != nil {
+			return err
+		}
+
+		writeHeader(bw, name, "counter")
+		series := r.counters[name]
+		for _, key := range sortedKeys(series) {
+			fmt.Fprintf(bw, "%s%s %s\n", name, wrapLabels(key), formatFloat(series[key]))
+		}
+	}
+
+	for _, name := range sortedKeys(r.histograms) {
+		if err := validateName(name); err != nil {
+			return err
+		}
+
+		writeHeader(bw, name, "histogram")
+		series := r.histograms[name]
+		for _, key := range sortedKeys(series) {
+			h := series[key]
+			for i, b := range r.buckets {
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", name, wrapLabels(joinLabels(key, fmt.Sprintf(`le="%s"`, formatFloat(b)))), h.counts[i])
+			}
+
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", name, wrapLabels(joinLabels(key, `le="+Inf"`)), h.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", name, wrapLabels(key), formatFloat(h.sum))
+			fmt.Fprintf(bw, "%s_count%s %d\n", name, wrapLabels(key), h.count)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP renders the metrics so the Registry can be used as /metrics handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.WritePrometheus(w); err != nil {
+		Logger().Error("unable to render prometheus metrics", "error", err)
+	}
+}
+
+func writeHeader(w io.Writer, name, metricType string) {
+	if help, ok := metricHelp[name]; ok {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	}
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// renderLabels renders labels sorted by name, e.g. code="200",endpoint="/status"
+func renderLabels(labels Labels) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if validateName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(labels[name]))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func joinLabels(labels, extra string) string {
+	if len(labels) == 0 {
+		return extra
+	}
+
+	return labels + "," + extra
+}
+
+func wrapLabels(labels string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	return "{" + labels + "}"
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
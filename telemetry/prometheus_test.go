@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryWritePrometheus(t *testing.T) {
+	registry := NewRegistryWithBuckets([]float64{1, 0.1})
+	tracer := NewMetricsTracer(registry)
+
+	info := RequestInfo{Method: "GET", Endpoint: "/{agreementId}/status"}
+	tracer.RequestEnd(info, RequestResult{StatusCode: 200, Duration: 50 * time.Millisecond})
+	tracer.RequestEnd(info, RequestResult{StatusCode: 200, Duration: 500 * time.Millisecond})
+	tracer.RequestEnd(info, RequestResult{Duration: 2 * time.Second, Err: errors.New("timeout")})
+	tracer.CacheLookup(CacheAccessToken, true)
+	tracer.CacheLookup(CacheAccessToken, false)
+	registry.IncCounter("custom_total", Labels{"value": "a \"quoted\"\nvalue"})
+
+	out := strings.Builder{}
+	if err := registry.WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus() = %v", err)
+	}
+
+	want := `# TYPE custom_total counter
+custom_total{value="a \"quoted\"\nvalue"} 1
+# HELP toon_api_requests_total Number of requests send to the Toon API by endpoint template and status code.
+# TYPE toon_api_requests_total counter
+toon_api_requests_total{code="200",endpoint="/{agreementId}/status",method="GET"} 2
+toon_api_requests_total{code="error",endpoint="/{agreementId}/status",method="GET"} 1
+# HELP toon_cache_lookups_total Number of cache lookups by cache and result.
+# TYPE toon_cache_lookups_total counter
+toon_cache_lookups_total{cache="access_token",result="hit"} 1
+toon_cache_lookups_total{cache="access_token",result="miss"} 1
+# HELP toon_api_request_duration_seconds Latency of requests send to the Toon API in seconds.
+# TYPE toon_api_request_duration_seconds histogram
+toon_api_request_duration_seconds_bucket{endpoint="/{agreementId}/status",method="GET",le="0.1"} 1
+toon_api_request_duration_seconds_bucket{endpoint="/{agreementId}/status",method="GET",le="1"} 2
+toon_api_request_duration_seconds_bucket{endpoint="/{agreementId}/status",method="GET",le="+Inf"} 3
+toon_api_request_duration_seconds_sum{endpoint="/{agreementId}/status",method="GET"} 2.55
+toon_api_request_duration_seconds_count{endpoint="/{agreementId}/status",method="GET"} 3
+`
+	if out.String() != want {
+		t.Fatalf("WritePrometheus() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRegistryInvalidMetricName(t *testing.T) {
+	registry := NewRegistry()
+	registry.IncCounter("invalid-name", nil)
+
+	if err := registry.WritePrometheus(&strings.Builder{}); !errors.Is(err, errInvalidMetricName) {
+		t.Fatalf("WritePrometheus() = %v, want %v", err, errInvalidMetricName)
+	}
+}
+
+func TestMultiTracerCacheLookups(t *testing.T) {
+	registry := NewRegistry()
+	TraceCacheLookup(MultiTracer(&minimalTracer{}, NewMetricsTracer(registry)), CacheAccessToken, true)
+
+	if got := registry.Counter(MetricCacheLookups, Labels{"cache": CacheAccessToken, "result": "hit"}); got != 1 {
+		t.Fatalf("cache hits %v, want 1", got)
+	}
+}
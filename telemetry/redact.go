@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted is the value logged in place of a secret
+const Redacted = "[REDACTED]"
+
+// secretKeys are header, query and form keys which are never logged
+var secretKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"password":      true,
+	"client_secret": true,
+	"code":          true,
+	"access_token":  true,
+	"refresh_token": true,
+	// webhook secrets, see toon.WebhookHandler
+	"secret":           true,
+	"x-toon-secret":    true,
+	"x-toon-signature": true,
+}
+
+// IsSecret returns true when a header, query or form key holds a secret
+func IsSecret(key string) bool {
+	return secretKeys[strings.ToLower(key)]
+}
+
+// Secret wraps a string so it is always redacted when logged with slog
+type Secret string
+
+// LogValue implements slog.LogValuer
+func (Secret) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// String implements fmt.Stringer so a Secret is also redacted when printed
+func (Secret) String() string {
+	return Redacted
+}
+
+// RedactHeader returns a copy of the header with all secret values replaced
+func RedactHeader(header http.Header) map[string][]string {
+	redacted := make(map[string][]string, len(header))
+	for k, v := range header {
+		if IsSecret(k) {
+			redacted[k] = []string{Redacted}
+			continue
+		}
+
+		redacted[k] = append([]string(nil), v...)
+	}
+
+	return redacted
+}
+
+// RedactValues returns a copy of query or form values with all secret values replaced
+func RedactValues(values url.Values) url.Values {
+	redacted := make(url.Values, len(values))
+	for k, v := range values {
+		if IsSecret(k) {
+			redacted[k] = []string{Redacted}
+			continue
+		}
+
+		redacted[k] = append([]string(nil), v...)
+	}
+
+	return redacted
+}
+
+// RedactURL returns the URL with user info and secret query values replaced
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Redacted
+	}
+
+	if u.User != nil {
+		u.User = url.User(Redacted)
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = RedactValues(u.Query()).Encode()
+	}
+
+	return u.String()
+}
@@ -0,0 +1,232 @@
+// Package telemetry contains the logging and tracing hooks used by the auth and toon packages.
+// By default nothing is logged or traced, use SetLogger and SetTracer to plug in your own.
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	mu     sync.RWMutex
+	logger        = slog.New(discardHandler{})
+	tracer Tracer = NopTracer{}
+)
+
+// SetLogger sets the logger used by the SDK, supply nil to disable logging
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	logger = l
+}
+
+// Logger returns the logger used by the SDK
+func Logger() *slog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger
+}
+
+// SetTracer sets the tracer which receives the SDK hooks, supply nil to disable tracing
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = NopTracer{}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	tracer = t
+}
+
+// ActiveTracer returns the tracer which receives the SDK hooks
+func ActiveTracer() Tracer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return tracer
+}
+
+// Tracer receives hooks for every API and authentication call made by the SDK.
+// Hooks are called synchronously so implementations should return quickly.
+type Tracer interface {
+	// RequestStart is called before a request is send to the Toon API
+	RequestStart(info RequestInfo)
+	// RequestEnd is called after a request is finished, also when it failed
+	RequestEnd(info RequestInfo, result RequestResult)
+	// RequestRetry is called when a request is retried, for instance after a token refresh
+	RequestRetry(info RequestInfo, attempt int, reason string)
+	// Auth is called after a step in the authentication flow is finished
+	Auth(event AuthEvent)
+	// CallbackReceived is called when the OAuth callback server receives a request
+	CallbackReceived(info CallbackInfo)
+}
+
+// RejectionTracer is implemented by tracers which want to know about requests which are not send, it is
+// separate from Tracer so existing tracers keep working. NopTracer implements it.
+type RejectionTracer interface {
+	// RequestRejected is called instead of RequestStart and RequestEnd when a request is not send
+	// because of the rate limiter or circuit breaker
+	RequestRejected(info RequestInfo, reason RejectReason, err error)
+}
+
+// TraceRejected calls RequestRejected when the tracer implements RejectionTracer
+func TraceRejected(t Tracer, info RequestInfo, reason RejectReason, err error) {
+	if rt, ok := t.(RejectionTracer); ok {
+		rt.RequestRejected(info, reason, err)
+	}
+}
+
+// CacheAccessToken is the cache name of lookups of the access token cached by the authenticator,
+// a request accepted with the cached token is a hit and a request with an expired token a miss
+const CacheAccessToken = "access_token"
+
+// CacheTracer is implemented by tracers which want to know about cache lookups, it is separate from
+// Tracer so existing tracers keep working. NopTracer implements it.
+type CacheTracer interface {
+	// CacheLookup is called when a cached value is looked up, hit is false on a cache miss
+	CacheLookup(cache string, hit bool)
+}
+
+// TraceCacheLookup calls CacheLookup when the tracer implements CacheTracer
+func TraceCacheLookup(t Tracer, cache string, hit bool) {
+	if ct, ok := t.(CacheTracer); ok {
+		ct.CacheLookup(cache, hit)
+	}
+}
+
+// RequestInfo describes a request to the Toon API, the URL and Header are redacted
+type RequestInfo struct {
+	Method string
+	// Endpoint is the endpoint template without any ids, e.g. /{agreementId}/status
+	Endpoint string
+	URL      string
+	Header   map[string][]string
+	Start    time.Time
+}
+
+// RequestResult describes the outcome of a request to the Toon API
+type RequestResult struct {
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// RejectReason is the reason a request was not send to the Toon API
+type RejectReason string
+
+// Reasons reported to RequestRejected
+const (
+	RejectRateLimit   RejectReason = "rate_limit"
+	RejectCircuitOpen RejectReason = "circuit_open"
+)
+
+// AuthStage is a step in the authentication flow
+type AuthStage string
+
+// Authentication steps reported in an AuthEvent
+const (
+	AuthStageLogin        AuthStage = "login"
+	AuthStageCodeExchange AuthStage = "code_exchange"
+	AuthStageRefresh      AuthStage = "refresh"
+)
+
+// AuthEvent describes the outcome of a step in the authentication flow
+type AuthEvent struct {
+	Stage      AuthStage
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// CallbackInfo describes a request received by the OAuth callback server
+type CallbackInfo struct {
+	RemoteAddr string
+	Path       string
+	HasCode    bool
+}
+
+// NopTracer is a Tracer that does nothing, embed it to implement only the hooks you need
+type NopTracer struct{}
+
+// RequestStart implements Tracer
+func (NopTracer) RequestStart(RequestInfo) {}
+
+// RequestEnd implements Tracer
+func (NopTracer) RequestEnd(RequestInfo, RequestResult) {}
+
+// RequestRetry implements Tracer
+func (NopTracer) RequestRetry(RequestInfo, int, string) {}
+
+// RequestRejected implements RejectionTracer
+func (NopTracer) RequestRejected(RequestInfo, RejectReason, error) {}
+
+// CacheLookup implements CacheTracer
+func (NopTracer) CacheLookup(string, bool) {}
+
+// Auth implements Tracer
+func (NopTracer) Auth(AuthEvent) {}
+
+// CallbackReceived implements Tracer
+func (NopTracer) CallbackReceived(CallbackInfo) {}
+
+// MultiTracer returns a Tracer that calls all given tracers in order
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(tracers)
+}
+
+type multiTracer []Tracer
+
+func (m multiTracer) RequestStart(info RequestInfo) {
+	for _, t := range m {
+		t.RequestStart(info)
+	}
+}
+
+func (m multiTracer) RequestEnd(info RequestInfo, result RequestResult) {
+	for _, t := range m {
+		t.RequestEnd(info, result)
+	}
+}
+
+func (m multiTracer) RequestRetry(info RequestInfo, attempt int, reason string) {
+	for _, t := range m {
+		t.RequestRetry(info, attempt, reason)
+	}
+}
+
+func (m multiTracer) RequestRejected(info RequestInfo, reason RejectReason, err error) {
+	for _, t := range m {
+		TraceRejected(t, info, reason, err)
+	}
+}
+
+func (m multiTracer) CacheLookup(cache string, hit bool) {
+	for _, t := range m {
+		TraceCacheLookup(t, cache, hit)
+	}
+}
+
+func (m multiTracer) Auth(event AuthEvent) {
+	for _, t := range m {
+		t.Auth(event)
+	}
+}
+
+func (m multiTracer) CallbackReceived(info CallbackInfo) {
+	for _, t := range m {
+		t.CallbackReceived(info)
+	}
+}
+
+// discardHandler is a slog.Handler that drops all records
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
@@ -0,0 +1,43 @@
+package telemetry
+
+import (
+	"errors"
+	"testing"
+)
+
+// minimalTracer implements only Tracer, as tracers written before RejectionTracer existed
+type minimalTracer struct {
+	ends int
+}
+
+func (t *minimalTracer) RequestStart(RequestInfo)              {}
+func (t *minimalTracer) RequestEnd(RequestInfo, RequestResult) { t.ends++ }
+func (t *minimalTracer) RequestRetry(RequestInfo, int, string) {}
+func (t *minimalTracer) Auth(AuthEvent)                        {}
+func (t *minimalTracer) CallbackReceived(CallbackInfo)         {}
+
+type rejectionTracer struct {
+	NopTracer
+	reasons []RejectReason
+}
+
+func (t *rejectionTracer) RequestRejected(info RequestInfo, reason RejectReason, err error) {
+	t.reasons = append(t.reasons, reason)
+}
+
+func TestMultiTracerRejections(t *testing.T) {
+	minimal, rejections := &minimalTracer{}, &rejectionTracer{}
+	tracer := MultiTracer(minimal, rejections)
+
+	tracer.RequestEnd(RequestInfo{}, RequestResult{})
+	TraceRejected(tracer, RequestInfo{}, RejectRateLimit, errors.New("rate limit reached"))
+	TraceRejected(minimal, RequestInfo{}, RejectCircuitOpen, errors.New("circuit breaker is open"))
+
+	if minimal.ends != 1 {
+		t.Fatalf("minimal tracer got %d request ends, want 1", minimal.ends)
+	}
+
+	if len(rejections.reasons) != 1 || rejections.reasons[0] != RejectRateLimit {
+		t.Fatalf("rejections %v, want [%v]", rejections.reasons, RejectRateLimit)
+	}
+}
@@ -0,0 +1,314 @@
+package toon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// BoostRetryDelay is the time to wait before retrying a failed revert
+var BoostRetryDelay = time.Minute
+
+// Boost is a temporary setpoint which is reverted to the previous thermostat state when it ends
+type Boost struct {
+	AgreementID string    `json:"agreementId"`
+	Setpoint    float64   `json:"setpoint"`
+	Until       time.Time `json:"until"`
+	// Previous state of the thermostat, restored when the boost ends
+	PreviousProgramState ProgramState `json:"previousProgramState"`
+	PreviousActiveState  Preset       `json:"previousActiveState"`
+	PreviousSetpoint     int          `json:"previousSetpoint"`
+}
+
+// BoostStore persists pending boosts so they can be reverted after a crash or restart
+type BoostStore interface {
+	Save(boost Boost) error
+	Delete(agreementID string) error
+	List() ([]Boost, error)
+}
+
+// Booster applies boosts and reverts them when they end, call Resume after
+// creating a Booster to revert or reschedule boosts from a previous run
+type Booster struct {
+	auth   *auth.ToonAuthenticator
+	store  BoostStore
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	// stopped prevents scheduling reverts after Stop until Resume
+	stopped bool
+	// OnRevert is called after a boost is reverted, err is not nil when the revert failed and will be retried
+	OnRevert func(boost Boost, err *ErrorResponse)
+}
+
+// NewBooster creates a new Booster which persists pending boosts in the store
+func NewBooster(auth *auth.ToonAuthenticator, store BoostStore) *Booster {
+	return &Booster{
+		auth:   auth,
+		store:  store,
+		timers: map[string]*time.Timer{},
+	}
+}
+
+// Start sets the setpoint in degrees Celsius for the given duration, after which the previous state
+// of the thermostat is restored. The weekly program is paused during the boost so a program change
+// does not end it early. Starting a boost while another boost is active extends it and keeps the
+// original previous state.
+func (b *Booster) Start(agreementID string, celsius float64, duration time.Duration) (*Boost, *ErrorResponse) {
+	if err := validateTemperature(celsius); err != nil {
+		return nil, newErrorResponse(err, "Invalid temperature")
+	}
+
+	if duration <= 0 {
+		return nil, newErrorResponse(fmt.Errorf("boost duration should be positive, got %v", duration), "Invalid duration")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	boost, err := b.pending(agreementID)
+	if err != nil {
+		return nil, newErrorResponse(err, "Unable to load boost")
+	}
+
+	var previous *Boost
+	if boost != nil {
+		extended := *boost
+		previous = &extended
+	} else {
+		info, errResponse := GetThermostatInfo(b.auth, agreementID)
+		if errResponse != nil {
+			return nil, errResponse
+		}
+
+		boost = &Boost{
+			AgreementID:          agreementID,
+			PreviousProgramState: info.Program(),
+			PreviousActiveState:  info.Active(),
+			PreviousSetpoint:     info.CurrentSetpoint,
+		}
+	}
+
+	boost.Setpoint = celsius
+	boost.Until = time.Now().Add(duration)
+
+	// save before applying so the boost is reverted even when the process crashes right after
+	if err := b.store.Save(*boost); err != nil {
+		return nil, newErrorResponse(err, "Unable to save boost")
+	}
+
+	if errResponse := UpdateCurrentTemperature(b.auth, agreementID, celsius, OverridePermanent); errResponse != nil {
+		// keep the boost which was extended so it is still reverted, also after a restart
+		if previous != nil {
+			err = b.store.Save(*previous)
+		} else {
+			err = b.store.Delete(agreementID)
+		}
+
+		if err != nil {
+			telemetry.Logger().Warn("unable to restore toon boost", "agreement_id", agreementID, "error", err)
+		}
+
+		return nil, errResponse
+	}
+
+	telemetry.Logger().Info("toon boost started", "agreement_id", agreementID, "setpoint", celsius, "until", boost.Until)
+	b.schedule(*boost, duration)
+	return boost, nil
+}
+
+// Cancel ends the boost of an agreement now and restores the previous state, when restoring
+// fails the boost stays pending and the revert is retried after BoostRetryDelay
+func (b *Booster) Cancel(agreementID string) *ErrorResponse {
+	b.mu.Lock()
+	b.stopTimer(agreementID)
+	boost, err := b.pending(agreementID)
+	if err != nil || boost == nil {
+		b.mu.Unlock()
+		if err != nil {
+			return newErrorResponse(err, "Unable to load boost")
+		}
+
+		return nil
+	}
+
+	errResponse := b.revert(*boost)
+	b.mu.Unlock()
+
+	b.reverted(*boost, errResponse)
+	return errResponse
+}
+
+// Pending returns the active boost of an agreement or nil when there is none
+func (b *Booster) Pending(agreementID string) (*Boost, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending(agreementID)
+}
+
+// Resume reverts boosts from the store which ended while the process was not running
+// and schedules the revert of boosts which are still active, also after Stop
+func (b *Booster) Resume() error {
+	boosts, err := b.store.List()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = false
+	for _, boost := range boosts {
+		b.schedule(boost, time.Until(boost.Until))
+	}
+
+	return nil
+}
+
+// Stop stops all scheduled reverts and retries, pending boosts stay in the store and can be resumed later
+func (b *Booster) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = true
+	for agreementID := range b.timers {
+		b.stopTimer(agreementID)
+	}
+}
+
+func (b *Booster) pending(agreementID string) (*Boost, error) {
+	boosts, err := b.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, boost := range boosts {
+		if boost.AgreementID == agreementID {
+			return &boost, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (b *Booster) stopTimer(agreementID string) {
+	if timer, ok := b.timers[agreementID]; ok {
+		timer.Stop()
+		delete(b.timers, agreementID)
+	}
+}
+
+// schedule schedules the revert of a boost, should be called while holding the lock. When the timer
+// fires after it was replaced, or the boost was cancelled or extended in the meantime, nothing is reverted.
+func (b *Booster) schedule(boost Boost, after time.Duration) {
+	b.stopTimer(boost.AgreementID)
+	if b.stopped {
+		return
+	}
+
+	if after < 0 {
+		after = 0
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(after, func() {
+		b.mu.Lock()
+		if b.timers[boost.AgreementID] != timer {
+			b.mu.Unlock()
+			return
+		}
+
+		delete(b.timers, boost.AgreementID)
+		current, err := b.pending(boost.AgreementID)
+		if err != nil {
+			telemetry.Logger().Warn("unable to load toon boost", "agreement_id", boost.AgreementID, "error", err, "retry_in", BoostRetryDelay)
+			b.schedule(boost, BoostRetryDelay)
+			b.mu.Unlock()
+			return
+		}
+
+		if current == nil || !current.Until.Equal(boost.Until) {
+			b.mu.Unlock()
+			return
+		}
+
+		errResponse := b.revert(*current)
+		b.mu.Unlock()
+		b.reverted(*current, errResponse)
+	})
+	b.timers[boost.AgreementID] = timer
+}
+
+// revert restores the thermostat state from before the boost and removes it from the store, should be called
+// while holding the lock. When this fails the revert is retried after BoostRetryDelay unless the Booster is stopped.
+func (b *Booster) revert(boost Boost) *ErrorResponse {
+	var err *ErrorResponse
+	mode := OverrideTemporary
+	if boost.PreviousProgramState == ProgramOff {
+		mode = OverridePermanent
+	}
+
+	switch {
+	case boost.PreviousProgramState == ProgramOn:
+		err = ResumeProgram(b.auth, boost.AgreementID)
+	case boost.PreviousActiveState != PresetNone:
+		err = SetActivePreset(b.auth, boost.AgreementID, boost.PreviousActiveState, mode)
+	default:
+		err = UpdateCurrentTemperature(b.auth, boost.AgreementID, toCelsius(boost.PreviousSetpoint), mode)
+	}
+
+	if err == nil {
+		if storeErr := b.store.Delete(boost.AgreementID); storeErr != nil {
+			err = newErrorResponse(storeErr, "Unable to delete boost")
+		}
+	}
+
+	if err != nil {
+		b.schedule(boost, BoostRetryDelay)
+	} else {
+		b.stopTimer(boost.AgreementID)
+	}
+
+	return err
+}
+
+// reverted reports the outcome of a revert, it is called without holding the lock so OnRevert can use the Booster
+func (b *Booster) reverted(boost Boost, err *ErrorResponse) {
+	if err != nil {
+		telemetry.Logger().Warn("unable to revert toon boost", "agreement_id", boost.AgreementID, "error", err, "retry_in", BoostRetryDelay)
+	} else {
+		telemetry.Logger().Info("toon boost reverted", "agreement_id", boost.AgreementID)
+	}
+
+	if b.OnRevert != nil {
+		b.OnRevert(boost, err)
+	}
+}
+
+// FileBoostStore is a BoostStore which keeps pending boosts in a JSON file
+type FileBoostStore struct {
+	Path  string
+	store fileStore[Boost]
+}
+
+// NewFileBoostStore creates a BoostStore which keeps pending boosts in the file at path
+func NewFileBoostStore(path string) *FileBoostStore {
+	return &FileBoostStore{Path: path}
+}
+
+// Save implements BoostStore
+func (s *FileBoostStore) Save(boost Boost) error {
+	return s.store.save(s.Path, boost.AgreementID, boost)
+}
+
+// Delete implements BoostStore
+func (s *FileBoostStore) Delete(agreementID string) error {
+	return s.store.delete(s.Path, agreementID)
+}
+
+// List implements BoostStore
+func (s *FileBoostStore) List() ([]Boost, error) {
+	return s.store.list(s.Path)
+}
@@ -0,0 +1,224 @@
+package toon
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+type memoryBoostStore struct {
+	mu     sync.Mutex
+	boosts map[string]Boost
+}
+
+func (s *memoryBoostStore) Save(boost Boost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.boosts[boost.AgreementID] = boost
+	return nil
+}
+
+func (s *memoryBoostStore) Delete(agreementID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.boosts, agreementID)
+	return nil
+}
+
+func (s *memoryBoostStore) List() ([]Boost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	boosts := []Boost{}
+	for _, boost := range s.boosts {
+		boosts = append(boosts, boost)
+	}
+
+	return boosts, nil
+}
+
+// thermostatServer is a fake thermostat endpoint which records the updates it receives
+type thermostatServer struct {
+	mu      sync.Mutex
+	updates []string
+	fail    atomic.Bool
+}
+
+func (s *thermostatServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.updates)
+}
+
+func newTestBooster(t *testing.T) (*Booster, *memoryBoostStore, *thermostatServer) {
+	thermostat := &thermostatServer{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/toon/v3/1/thermostat" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"currentSetpoint":1800,"programState":1,"activeState":-1}`))
+			return
+		}
+
+		if thermostat.fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		thermostat.mu.Lock()
+		thermostat.updates = append(thermostat.updates, string(body))
+		thermostat.mu.Unlock()
+	})
+
+	retryDelay := BoostRetryDelay
+	BoostRetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { BoostRetryDelay = retryDelay })
+
+	store := &memoryBoostStore{boosts: map[string]Boost{}}
+	booster := NewBooster(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0), store)
+	t.Cleanup(booster.Stop)
+	return booster, store, thermostat
+}
+
+func TestBoosterCancel(t *testing.T) {
+	booster, store, thermostat := newTestBooster(t)
+	reverts := 0
+	booster.OnRevert = func(boost Boost, err *ErrorResponse) { reverts++ }
+
+	if _, err := booster.Start("1", 21, time.Hour); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	if err := booster.Cancel("1"); err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+
+	// the second cancel has nothing left to revert
+	if err := booster.Cancel("1"); err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+
+	if boosts, _ := store.List(); len(boosts) != 0 {
+		t.Fatalf("store has %d boosts after Cancel, want 0", len(boosts))
+	}
+
+	// the boost is reverted once by resuming the program
+	if want := `{"programState":1}`; reverts != 1 || len(thermostat.updates) != 2 || thermostat.updates[1] != want {
+		t.Fatalf("got %d reverts and updates %v, want 1 revert ending with %s", reverts, thermostat.updates, want)
+	}
+
+	if len(booster.timers) != 0 {
+		t.Fatalf("%d timers left after Cancel, want 0", len(booster.timers))
+	}
+}
+
+func TestBoosterFailedExtendKeepsBoost(t *testing.T) {
+	booster, _, thermostat := newTestBooster(t)
+
+	boost, err := booster.Start("1", 21, time.Hour)
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	thermostat.fail.Store(true)
+	if _, err := booster.Start("1", 22, 2*time.Hour); err == nil {
+		t.Fatal("Start() extended the boost while the thermostat update failed")
+	}
+
+	pending, storeErr := booster.Pending("1")
+	if storeErr != nil || pending == nil {
+		t.Fatalf("Pending() = %v, %v, want the original boost", pending, storeErr)
+	}
+
+	if *pending != *boost {
+		t.Fatalf("Pending() = %+v, want %+v", *pending, *boost)
+	}
+}
+
+func TestBoosterExtendReplacesRevert(t *testing.T) {
+	booster, _, thermostat := newTestBooster(t)
+
+	if _, err := booster.Start("1", 21, 20*time.Millisecond); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	if _, err := booster.Start("1", 22, time.Hour); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	// the timer of the first boost must not revert the extended boost
+	time.Sleep(60 * time.Millisecond)
+	if pending, _ := booster.Pending("1"); pending == nil || pending.Setpoint != 22 {
+		t.Fatalf("Pending() = %+v, want the extended boost", pending)
+	}
+
+	if count := thermostat.count(); count != 2 {
+		t.Fatalf("thermostat received %d updates, want 2", count)
+	}
+}
+
+func TestBoosterRevertAfterEnd(t *testing.T) {
+	booster, store, thermostat := newTestBooster(t)
+	reverted := make(chan *ErrorResponse, 1)
+	booster.OnRevert = func(boost Boost, err *ErrorResponse) { reverted <- err }
+
+	if _, err := booster.Start("1", 21, 10*time.Millisecond); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	select {
+	case err := <-reverted:
+		if err != nil {
+			t.Fatalf("OnRevert(%v), want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("boost was not reverted")
+	}
+
+	if boosts, _ := store.List(); len(boosts) != 0 || thermostat.count() != 2 {
+		t.Fatalf("got %d boosts and %d updates, want 0 and 2", len(boosts), thermostat.count())
+	}
+}
+
+func TestBoosterStopEndsRetries(t *testing.T) {
+	booster, store, thermostat := newTestBooster(t)
+	reverts := atomic.Int32{}
+	booster.OnRevert = func(boost Boost, err *ErrorResponse) { reverts.Add(1) }
+
+	if _, err := booster.Start("1", 21, time.Hour); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	// the failed cancel schedules a retry, which Stop must end
+	thermostat.fail.Store(true)
+	if err := booster.Cancel("1"); err == nil {
+		t.Fatal("Cancel() succeeded while the thermostat update failed")
+	}
+
+	booster.Stop()
+	time.Sleep(50 * time.Millisecond)
+	if count := reverts.Load(); count != 1 {
+		t.Fatalf("%d reverts after Stop, want 1", count)
+	}
+
+	// a failed revert after Stop does not schedule a retry either
+	if err := booster.Cancel("1"); err == nil {
+		t.Fatal("Cancel() succeeded while the thermostat update failed")
+	}
+
+	if len(booster.timers) != 0 {
+		t.Fatalf("%d timers after Stop, want 0", len(booster.timers))
+	}
+
+	if boosts, _ := store.List(); len(boosts) != 1 {
+		t.Fatalf("store has %d boosts, want the pending boost", len(boosts))
+	}
+}
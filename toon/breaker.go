@@ -0,0 +1,218 @@
+package toon
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is not send because the circuit breaker
+// for the host is open, check with errors.Is(err, toon.ErrCircuitOpen)
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+var circuitBreaker atomic.Pointer[CircuitBreaker]
+
+// SetCircuitBreaker sets the circuit breaker used for all requests to the Toon API,
+// supply nil to disable the circuit breaker
+func SetCircuitBreaker(cb *CircuitBreaker) {
+	circuitBreaker.Store(cb)
+}
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+// Circuit breaker states
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all requests fast with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through
+	CircuitHalfOpen
+)
+
+var circuitStates = [...]string{
+	"closed",
+	"open",
+	"half-open",
+}
+
+// String() function will return the name of a circuit state
+func (s CircuitState) String() string {
+	return circuitStates[s]
+}
+
+// CircuitBreakerConfig contains the settings of a CircuitBreaker, zero values are replaced by the defaults
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures before the circuit opens, default 5
+	FailureThreshold int
+	// OpenTimeout is the time the circuit stays open before probing, default 30 seconds
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of successful probe requests needed to close the circuit, default 1
+	HalfOpenProbes int
+	// OnStateChange is called when the circuit of a host changes state
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// CircuitBreaker keeps a circuit per host, a request counts as failed when no response
+// was received or the Toon API responded with a 5xx status code
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+	mu     sync.Mutex
+	hosts  map[string]*circuit
+	now    func() time.Time
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int
+	probes    int
+	successes int
+	openedAt  time.Time
+	// generation is incremented on every state change
+	generation uint64
+}
+
+// admission is returned by allow for every allowed request, only the outcome of requests
+// allowed in the current generation of the circuit is counted
+type admission struct {
+	generation uint64
+}
+
+// NewCircuitBreaker creates a new circuit breaker, use SetCircuitBreaker to enable it
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+
+	return &CircuitBreaker{
+		config: config,
+		hosts:  map[string]*circuit{},
+		now:    time.Now,
+	}
+}
+
+// State returns the current state of the circuit for a host
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.hosts[host]
+	if !ok {
+		return CircuitClosed
+	}
+
+	if c.state == CircuitOpen && cb.now().Sub(c.openedAt) >= cb.config.OpenTimeout {
+		return CircuitHalfOpen
+	}
+
+	return c.state
+}
+
+// allow returns ErrCircuitOpen when a request to the host is not allowed, the admission
+// of an allowed request should be passed to report with the outcome of the request
+func (cb *CircuitBreaker) allow(host string) (admission, error) {
+	cb.mu.Lock()
+	c := cb.circuit(host)
+	var change func()
+
+	switch c.state {
+	case CircuitOpen:
+		if cb.now().Sub(c.openedAt) < cb.config.OpenTimeout {
+			cb.mu.Unlock()
+			return admission{}, ErrCircuitOpen
+		}
+
+		change = cb.setState(host, c, CircuitHalfOpen)
+		c.probes = 1
+	case CircuitHalfOpen:
+		if c.probes >= cb.config.HalfOpenProbes {
+			cb.mu.Unlock()
+			return admission{}, ErrCircuitOpen
+		}
+
+		c.probes++
+	}
+
+	a := admission{generation: c.generation}
+	cb.mu.Unlock()
+	if change != nil {
+		change()
+	}
+
+	return a, nil
+}
+
+// report records the outcome of a request which was allowed. Outcomes of requests allowed before the
+// last state change are ignored, so while half-open only the probes count.
+func (cb *CircuitBreaker) report(host string, a admission, success bool) {
+	cb.mu.Lock()
+	c := cb.circuit(host)
+	if a.generation != c.generation {
+		cb.mu.Unlock()
+		return
+	}
+
+	var change func()
+	switch {
+	case success && c.state == CircuitHalfOpen:
+		c.successes++
+		c.probes--
+		if c.successes >= cb.config.HalfOpenProbes {
+			change = cb.setState(host, c, CircuitClosed)
+		}
+	case success:
+		c.failures = 0
+	case c.state == CircuitHalfOpen:
+		change = cb.setState(host, c, CircuitOpen)
+	case c.state == CircuitClosed:
+		c.failures++
+		if c.failures >= cb.config.FailureThreshold {
+			change = cb.setState(host, c, CircuitOpen)
+		}
+	}
+
+	cb.mu.Unlock()
+	if change != nil {
+		change()
+	}
+}
+
+func (cb *CircuitBreaker) circuit(host string) *circuit {
+	c, ok := cb.hosts[host]
+	if !ok {
+		c = &circuit{}
+		cb.hosts[host] = c
+	}
+
+	return c
+}
+
+// setState changes the state while holding the lock, the returned function
+// calls OnStateChange and should be called after unlocking
+func (cb *CircuitBreaker) setState(host string, c *circuit, to CircuitState) func() {
+	from := c.state
+	c.state = to
+	c.failures = 0
+	c.probes = 0
+	c.successes = 0
+	c.generation++
+	if to == CircuitOpen {
+		c.openedAt = cb.now()
+	}
+
+	return func() {
+		if cb.config.OnStateChange != nil {
+			cb.config.OnStateChange(host, from, to)
+		}
+	}
+}
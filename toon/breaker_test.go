@@ -0,0 +1,127 @@
+package toon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	changes := []CircuitState{}
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   1,
+		OnStateChange:    func(host string, from, to CircuitState) { changes = append(changes, to) },
+	})
+	cb.now = func() time.Time { return now }
+
+	const host = "api.toon.eu"
+	steps := []struct {
+		name    string
+		advance time.Duration
+		// report is nil when allow is called, unless only the state is checked
+		report    *bool
+		stateOnly bool
+		allow     error
+		state     CircuitState
+	}{
+		{name: "closed allows", allow: nil, state: CircuitClosed},
+		{name: "first failure", report: ptr(false), state: CircuitClosed},
+		{name: "success resets failures", report: ptr(true), state: CircuitClosed},
+		{name: "failure", report: ptr(false), state: CircuitClosed},
+		{name: "threshold opens", report: ptr(false), state: CircuitOpen},
+		{name: "open rejects", allow: ErrCircuitOpen, state: CircuitOpen},
+		{name: "half-open after timeout", advance: time.Minute, stateOnly: true, state: CircuitHalfOpen},
+		{name: "probe allowed", allow: nil, state: CircuitHalfOpen},
+		{name: "second probe rejected", allow: ErrCircuitOpen, state: CircuitHalfOpen},
+		{name: "failed probe opens", report: ptr(false), state: CircuitOpen},
+		{name: "probe after timeout", advance: time.Minute, allow: nil, state: CircuitHalfOpen},
+		{name: "successful probe closes", report: ptr(true), state: CircuitClosed},
+	}
+
+	// reports are for the last allowed request
+	var allowed admission
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		switch {
+		case step.stateOnly:
+		case step.report != nil:
+			cb.report(host, allowed, *step.report)
+		default:
+			a, err := cb.allow(host)
+			if !errors.Is(err, step.allow) {
+				t.Fatalf("%v: allow() = %v, want %v", step.name, err, step.allow)
+			}
+
+			if err == nil {
+				allowed = a
+			}
+		}
+
+		if got := cb.State(host); got != step.state {
+			t.Fatalf("%v: State() = %v, want %v", step.name, got, step.state)
+		}
+	}
+
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes %v, want %v", changes, want)
+	}
+
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("state changes %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestCircuitBreakerHosts(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1})
+	a, _ := cb.allow("a")
+	cb.report("a", a, false)
+	if cb.State("a") != CircuitOpen || cb.State("b") != CircuitClosed {
+		t.Fatalf("State(a) = %v, State(b) = %v", cb.State("a"), cb.State("b"))
+	}
+
+	if _, err := cb.allow("b"); err != nil {
+		t.Fatalf("allow(b) = %v", err)
+	}
+}
+
+func TestCircuitBreakerLateReports(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+	cb.now = func() time.Time { return now }
+
+	const host = "api.toon.eu"
+	slow, _ := cb.allow(host)
+	failed, _ := cb.allow(host)
+	cb.report(host, failed, false)
+
+	now = now.Add(time.Minute)
+	probe, err := cb.allow(host)
+	if err != nil {
+		t.Fatalf("allow() = %v, want a probe", err)
+	}
+
+	// a request allowed while closed is not a probe, its success does not close the circuit
+	cb.report(host, slow, true)
+	if got := cb.State(host); got != CircuitHalfOpen {
+		t.Fatalf("State() = %v after a late report, want %v", got, CircuitHalfOpen)
+	}
+
+	if _, err := cb.allow(host); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() = %v while the probe is in flight, want %v", err, ErrCircuitOpen)
+	}
+
+	cb.report(host, probe, true)
+	if got := cb.State(host); got != CircuitClosed {
+		t.Fatalf("State() = %v after the probe, want %v", got, CircuitClosed)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,192 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// ErrRangePartiallyFetched is returned when one or more windows of a chunked range fetch failed
+var ErrRangePartiallyFetched = errors.New("range partially fetched")
+
+// ErrRangeNotFetched is returned when every window of a chunked range fetch failed
+var ErrRangeNotFetched = errors.New("range not fetched")
+
+// DefaultChunkConcurrency is the number of windows fetched at the same time
+const DefaultChunkConcurrency = 4
+
+// ChunkConfig contains the settings of a chunked range fetch
+type ChunkConfig struct {
+	// Window is the length of the windows the range is split in, default and at most the MaxSpan of IntervalNone
+	Window time.Duration
+	// Concurrency is the maximum number of windows fetched at the same time, default DefaultChunkConcurrency
+	Concurrency int
+}
+
+// Split splits the time range into successive windows of at most the given length, the last window
+// ends at the end of the range
+func (r TimeRange) Split(window time.Duration) []TimeRange {
+	windows := []TimeRange{}
+	if window <= 0 || !r.End.After(r.Start) {
+		return windows
+	}
+
+	for start := r.Start; start.Before(r.End); start = start.Add(window) {
+		end := start.Add(window)
+		if end.After(r.End) {
+			end = r.End
+		}
+
+		windows = append(windows, TimeRange{Start: start, End: end})
+	}
+
+	return windows
+}
+
+// ChunkResult is the outcome of fetching one window of a chunked range fetch
+type ChunkResult struct {
+	Range TimeRange      `json:"range"`
+	Err   *ErrorResponse `json:"error,omitempty"`
+}
+
+// ChunkedFlowData is the result of a chunked range fetch, the data of all windows is merged
+// ordered by timestamp without duplicates
+type ChunkedFlowData struct {
+	Data   FlowData      `json:"data"`
+	Chunks []ChunkResult `json:"chunks"`
+}
+
+// Failed returns the windows which could not be fetched
+func (c ChunkedFlowData) Failed() []ChunkResult {
+	failed := []ChunkResult{}
+	for _, chunk := range c.Chunks {
+		if chunk.Err != nil {
+			failed = append(failed, chunk)
+		}
+	}
+
+	return failed
+}
+
+// GetGasFlowDataChunked returns the 5 minute gas consumption for a time range of any length, see FetchFlowDataChunked
+func GetGasFlowDataChunked(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, config ChunkConfig) (*ChunkedFlowData, *ErrorResponse) {
+	return FetchFlowDataChunked(r, config, func(window TimeRange) (*FlowData, *ErrorResponse) {
+		return GetGasFlowDataRange(auth, agreementID, window)
+	})
+}
+
+// GetElectricityFlowDataChunked returns the 5 minute electricity consumption for a time range of any length,
+// see FetchFlowDataChunked
+func GetElectricityFlowDataChunked(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, config ChunkConfig) (*ChunkedFlowData, *ErrorResponse) {
+	return FetchFlowDataChunked(r, config, func(window TimeRange) (*FlowData, *ErrorResponse) {
+		return GetElectricityFlowDataRange(auth, agreementID, window)
+	})
+}
+
+// FetchFlowDataChunked splits a time range into windows which the API can return at once and fetches them with
+// bounded concurrency using fetch, e.g. one of the Range variants of the flow data calls, fetch is called from
+// multiple goroutines at the same time. When fetching a window fails the other windows are still fetched, the
+// result contains the outcome per window and the returned error wraps ErrRangePartiallyFetched, or
+// ErrRangeNotFetched when no window could be fetched. A Window longer than the MaxSpan of IntervalNone is rejected.
+func FetchFlowDataChunked(r TimeRange, config ChunkConfig, fetch func(window TimeRange) (*FlowData, *ErrorResponse)) (*ChunkedFlowData, *ErrorResponse) {
+	if r.Start.IsZero() || !r.End.After(r.Start) {
+		return nil, invalidTimeRange(fmt.Errorf("%w: end %v should be after start %v", ErrInvalidTimeRange, r.End, r.Start))
+	}
+
+	if config.Window <= 0 {
+		config.Window = IntervalNone.MaxSpan()
+	}
+
+	if config.Window > IntervalNone.MaxSpan() {
+		return nil, invalidTimeRange(fmt.Errorf("%w: window %v is longer than the maximum of %v", ErrInvalidTimeRange, config.Window, IntervalNone.MaxSpan()))
+	}
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultChunkConcurrency
+	}
+
+	windows := r.Split(config.Window)
+	data := make([]*FlowData, len(windows))
+	result := &ChunkedFlowData{Chunks: make([]ChunkResult, len(windows))}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.Concurrency)
+	for i, window := range windows {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, window TimeRange) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			flowData, err := fetch(window)
+			result.Chunks[i] = ChunkResult{Range: window, Err: err}
+			if err == nil {
+				data[i] = flowData
+			}
+		}(i, window)
+	}
+	wg.Wait()
+
+	result.Data = mergeFlowData(data)
+	failed := result.Failed()
+	if len(failed) == 0 {
+		return result, nil
+	}
+
+	sentinel, code := ErrRangePartiallyFetched, "Range partially fetched"
+	if len(failed) == len(windows) {
+		sentinel, code = ErrRangeNotFetched, "Range not fetched"
+	}
+
+	errs := []error{sentinel}
+	for _, chunk := range failed {
+		errs = append(errs, fmt.Errorf("%v - %v: %w", chunk.Range.Start, chunk.Range.End, chunk.Err))
+	}
+
+	telemetry.Logger().Warn("toon "+sentinel.Error(), "windows", len(windows), "failed", len(failed))
+	return result, newErrorResponse(errors.Join(errs...), code)
+}
+
+// mergeFlowData merges the data of successive windows, values are ordered by timestamp and
+// values with the same timestamp are only kept once
+func mergeFlowData(data []*FlowData) FlowData {
+	merged := FlowData{}
+	for _, d := range data {
+		if d == nil {
+			continue
+		}
+
+		merged.Hours = append(merged.Hours, d.Hours...)
+		merged.Days = append(merged.Days, d.Days...)
+		merged.Weeks = append(merged.Weeks, d.Weeks...)
+		merged.Months = append(merged.Months, d.Months...)
+		merged.Years = append(merged.Years, d.Years...)
+	}
+
+	merged.Hours = dedupFlowDataValues(merged.Hours)
+	merged.Days = dedupFlowDataValues(merged.Days)
+	merged.Weeks = dedupFlowDataValues(merged.Weeks)
+	merged.Months = dedupFlowDataValues(merged.Months)
+	merged.Years = dedupFlowDataValues(merged.Years)
+	return merged
+}
+
+func dedupFlowDataValues(values []FlowDataValue) []FlowDataValue {
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Timestamp < values[j].Timestamp })
+
+	deduped := []FlowDataValue{}
+	for i, value := range values {
+		if i > 0 && value.Timestamp == values[i-1].Timestamp {
+			continue
+		}
+
+		deduped = append(deduped, value)
+	}
+
+	return deduped
+}
@@ -0,0 +1,67 @@
+package toon
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchFlowDataChunked(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, Amsterdam)
+	r := TimeRange{Start: start, End: start.Add(3 * IntervalNone.MaxSpan())}
+	value := func(t time.Time) FlowDataValue { return FlowDataValue{Timestamp: t.UnixMilli(), Value: 1} }
+
+	tests := []struct {
+		name   string
+		config ChunkConfig
+		fail   func(window TimeRange) bool
+		want   error
+		values int
+		failed int
+	}{
+		{name: "all windows", fail: func(TimeRange) bool { return false }, values: 4},
+		{name: "one window failed", fail: func(w TimeRange) bool { return w.Start.Equal(start) }, want: ErrRangePartiallyFetched, values: 3, failed: 1},
+		{name: "every window failed", fail: func(TimeRange) bool { return true }, want: ErrRangeNotFetched, failed: 3},
+		{name: "window too long", config: ChunkConfig{Window: IntervalNone.MaxSpan() + time.Hour}, want: ErrInvalidTimeRange},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			result, err := FetchFlowDataChunked(r, test.config, func(window TimeRange) (*FlowData, *ErrorResponse) {
+				calls.Add(1)
+				if test.fail(window) {
+					return nil, newErrorResponse(errors.New("failed"), "Failed")
+				}
+
+				// successive windows share the boundary value which is only kept once
+				return &FlowData{Hours: []FlowDataValue{value(window.Start), value(window.End)}}, nil
+			})
+
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("FetchFlowDataChunked() = %v, want nil", err)
+				}
+			} else if err == nil || !errors.Is(err.Err, test.want) {
+				t.Fatalf("FetchFlowDataChunked() = %v, want %v", err, test.want)
+			}
+
+			if result == nil {
+				if calls.Load() != 0 {
+					t.Fatalf("fetched %d windows without result", calls.Load())
+				}
+
+				return
+			}
+
+			if len(result.Chunks) != 3 || len(result.Failed()) != test.failed {
+				t.Fatalf("got %d chunks with %d failed, want 3 with %d failed", len(result.Chunks), len(result.Failed()), test.failed)
+			}
+
+			if len(result.Data.Hours) != test.values {
+				t.Fatalf("got %d values, want %d", len(result.Data.Hours), test.values)
+			}
+		})
+	}
+}
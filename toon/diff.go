@@ -0,0 +1,280 @@
+package toon
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Change is a difference between two values, Path is built from the JSON names of the fields,
+// elements of slices are identified by their DevUUID or ID when they have one and by index otherwise,
+// e.g. deviceStatusInfo.device[abc-123].currentState or thermostatStates.state[2].tempValue.
+// Old is nil when an element was added and New is nil when it was removed.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// DiffOptions contains the settings of a diff
+type DiffOptions struct {
+	// Ignore contains paths which are not compared, a path also ignores everything below it
+	// and [*] matches any element, e.g. deviceStatusInfo.device[*].dayUsage
+	Ignore []string
+}
+
+// DefaultStatusDiffOptions ignores the fields of Status which change on every request
+func DefaultStatusDiffOptions() DiffOptions {
+	return DiffOptions{Ignore: []string{"serverTime"}}
+}
+
+// DiffStatus returns the changes between two status snapshots using DefaultStatusDiffOptions,
+// a nil status is compared as an empty status
+func DiffStatus(old, new *Status) []Change {
+	if old == nil {
+		old = &Status{}
+	}
+
+	if new == nil {
+		new = &Status{}
+	}
+
+	return Diff(*old, *new, DefaultStatusDiffOptions())
+}
+
+// Diff returns the changes between two values of a model type such as Status or ThermostatInfo,
+// the changes are ordered by field order
+func Diff[T any](old, new T, options DiffOptions) []Change {
+	d := differ{options: options, changes: []Change{}}
+	d.diff("", reflect.ValueOf(old), reflect.ValueOf(new))
+	return d.changes
+}
+
+var elementKeys = regexp.MustCompile(`\[[^\]]*\]`)
+
+type differ struct {
+	options DiffOptions
+	changes []Change
+}
+
+func (d *differ) ignored(path string) bool {
+	wildcard := elementKeys.ReplaceAllString(path, "[*]")
+	for _, ignore := range d.options.Ignore {
+		for _, p := range []string{path, wildcard} {
+			if p == ignore || strings.HasPrefix(p, ignore+".") || strings.HasPrefix(p, ignore+"[") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (d *differ) add(path string, old, new reflect.Value) {
+	change := Change{Path: path}
+	if old.IsValid() {
+		change.Old = old.Interface()
+	}
+
+	if new.IsValid() {
+		change.New = new.Interface()
+	}
+
+	d.changes = append(d.changes, change)
+}
+
+func (d *differ) diff(path string, old, new reflect.Value) {
+	if d.ignored(path) {
+		return
+	}
+
+	// nil interfaces have no value and values of different types can not be compared field by field
+	if !old.IsValid() || !new.IsValid() || old.Type() != new.Type() {
+		if old.IsValid() || new.IsValid() {
+			d.add(path, old, new)
+		}
+
+		return
+	}
+
+	if old.Type() == reflect.TypeOf(time.Time{}) {
+		if !old.Interface().(time.Time).Equal(new.Interface().(time.Time)) {
+			d.add(path, old, new)
+		}
+
+		return
+	}
+
+	switch old.Kind() {
+	case reflect.Struct:
+		d.diffStruct(path, old, new)
+	case reflect.Slice:
+		if old.Type().Elem().Kind() == reflect.Uint8 {
+			if !bytes.Equal(old.Bytes(), new.Bytes()) {
+				d.add(path, old, new)
+			}
+
+			return
+		}
+
+		d.diffSlice(path, old, new)
+	case reflect.Map:
+		d.diffMap(path, old, new)
+	case reflect.Pointer:
+		if old.IsNil() || new.IsNil() {
+			if old.IsNil() != new.IsNil() {
+				d.add(path, old, new)
+			}
+
+			return
+		}
+
+		d.diff(path, old.Elem(), new.Elem())
+	default:
+		if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+			d.add(path, old, new)
+		}
+	}
+}
+
+func (d *differ) diffStruct(path string, old, new reflect.Value) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		d.diff(joinPath(path, name), old.Field(i), new.Field(i))
+	}
+}
+
+func (d *differ) diffSlice(path string, old, new reflect.Value) {
+	oldKeys, oldKeyed := elementKeysOf(old)
+	newKeys, newKeyed := elementKeysOf(new)
+	if !oldKeyed || !newKeyed {
+		for i := 0; i < old.Len() || i < new.Len(); i++ {
+			element := fmt.Sprintf("%v[%d]", path, i)
+			switch {
+			case d.ignored(element):
+			case i >= new.Len():
+				d.add(element, old.Index(i), reflect.Value{})
+			case i >= old.Len():
+				d.add(element, reflect.Value{}, new.Index(i))
+			default:
+				d.diff(element, old.Index(i), new.Index(i))
+			}
+		}
+
+		return
+	}
+
+	newIndex := map[string]int{}
+	for i, key := range newKeys {
+		newIndex[key] = i
+	}
+
+	oldIndex := map[string]int{}
+	for i, key := range oldKeys {
+		oldIndex[key] = i
+		element := fmt.Sprintf("%v[%v]", path, key)
+		if j, ok := newIndex[key]; ok {
+			d.diff(element, old.Index(i), new.Index(j))
+		} else if !d.ignored(element) {
+			d.add(element, old.Index(i), reflect.Value{})
+		}
+	}
+
+	for j, key := range newKeys {
+		element := fmt.Sprintf("%v[%v]", path, key)
+		if _, ok := oldIndex[key]; !ok && !d.ignored(element) {
+			d.add(element, reflect.Value{}, new.Index(j))
+		}
+	}
+}
+
+func (d *differ) diffMap(path string, old, new reflect.Value) {
+	keys := map[string]reflect.Value{}
+	for _, key := range append(old.MapKeys(), new.MapKeys()...) {
+		keys[fmt.Sprint(key.Interface())] = key
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		element := joinPath(path, name)
+		o, n := old.MapIndex(keys[name]), new.MapIndex(keys[name])
+		switch {
+		case !o.IsValid() || !n.IsValid():
+			if !d.ignored(element) {
+				d.add(element, o, n)
+			}
+		default:
+			d.diff(element, o, n)
+		}
+	}
+}
+
+// elementKeysOf returns the DevUUID or ID of every element of a slice of structs,
+// false is returned when the elements have no key or a key is empty or not unique
+func elementKeysOf(slice reflect.Value) ([]string, bool) {
+	elem := slice.Type().Elem()
+	if elem.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	field := ""
+	for _, name := range []string{"DevUUID", "ID"} {
+		if _, ok := elem.FieldByName(name); ok {
+			field = name
+			break
+		}
+	}
+
+	if len(field) == 0 {
+		return nil, false
+	}
+
+	keys := make([]string, slice.Len())
+	seen := map[string]bool{}
+	for i := range keys {
+		keys[i] = fmt.Sprint(slice.Index(i).FieldByName(field).Interface())
+		if len(keys[i]) == 0 || seen[keys[i]] {
+			return nil, false
+		}
+
+		seen[keys[i]] = true
+	}
+
+	return keys, true
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if len(name) == 0 {
+		// fields without tag, such as those of SmokeDetector, are named like the tagged fields
+		return strings.ToLower(field.Name[:1]) + field.Name[1:]
+	}
+
+	return name
+}
+
+func joinPath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,95 @@
+package toon
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffStatus(t *testing.T) {
+	old := &Status{ServerTime: 1, ThermostatInfo: ThermostatInfo{CurrentSetpoint: 2000}}
+	old.DeviceStatusInfo.Status = []DeviceStatus{{DevUUID: "a", DayUsage: 1}, {DevUUID: "b"}}
+	old.SmokeDetectors.Devices = []SmokeDetector{{DevUUID: "s1", BatteryLevel: 50}}
+
+	new := &Status{ServerTime: 2, ThermostatInfo: ThermostatInfo{CurrentSetpoint: 2100}}
+	// reordered and with a device added and removed
+	new.DeviceStatusInfo.Status = []DeviceStatus{{DevUUID: "c"}, {DevUUID: "a", CurrentState: 1, DayUsage: 2}}
+	new.SmokeDetectors.Devices = []SmokeDetector{{DevUUID: "s1", BatteryLevel: 40, Extra: map[string]json.RawMessage{"x": json.RawMessage("1")}}}
+
+	tests := []struct {
+		name    string
+		changes []Change
+		want    []string
+	}{
+		{"default options", DiffStatus(old, new), []string{
+			"thermostatInfo.currentSetpoint",
+			"smokeDetectors.device[s1].batteryLevel",
+			"smokeDetectors.device[s1].extra.x",
+			"deviceStatusInfo.device[a].dayUsage",
+			"deviceStatusInfo.device[a].currentState",
+			"deviceStatusInfo.device[b]",
+			"deviceStatusInfo.device[c]",
+		}},
+		{"ignore paths", Diff(*old, *new, DiffOptions{Ignore: []string{"serverTime", "smokeDetectors", "deviceStatusInfo.device[*].dayUsage", "deviceStatusInfo.device[c]"}}), []string{
+			"thermostatInfo.currentSetpoint",
+			"deviceStatusInfo.device[a].currentState",
+			"deviceStatusInfo.device[b]",
+		}},
+		{"no options", Diff(Status{ServerTime: 1}, Status{ServerTime: 2}, DiffOptions{}), []string{"serverTime"}},
+		{"equal", DiffStatus(old, old), []string{}},
+		{"nil status", DiffStatus(nil, &Status{ServerTime: 5}), []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := []string{}
+			for _, change := range test.changes {
+				paths = append(paths, change.Path)
+			}
+
+			if !reflect.DeepEqual(paths, test.want) {
+				t.Fatalf("paths = %v, want %v", paths, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	changes := DiffStatus(&Status{PowerUsage: PowerUsage{Value: 300}}, &Status{PowerUsage: PowerUsage{Value: 450}})
+	if len(changes) != 1 || changes[0].Old != 300 || changes[0].New != 450 {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	added := Diff(DeviceStatusInfo{}, DeviceStatusInfo{Status: []DeviceStatus{{DevUUID: "a"}}}, DiffOptions{})
+	if len(added) != 1 || added[0].Old != nil || added[0].New.(DeviceStatus).DevUUID != "a" {
+		t.Fatalf("added = %+v", added)
+	}
+}
+
+func TestDiffInterfaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     int
+	}{
+		{"both nil", nil, nil, 0},
+		{"added", nil, 1, 1},
+		{"removed", "a", nil, 1},
+		{"different types", 1, "1", 1},
+		{"equal", 1, 1, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Diff[any](test.old, test.new, DiffOptions{}); len(got) != test.want {
+				t.Fatalf("Diff(%v, %v) = %+v", test.old, test.new, got)
+			}
+		})
+	}
+
+	// a nil interface field such as NetworkHealthState
+	changes := Diff(DeviceStatus{}, DeviceStatus{NetworkHealthState: "ok"}, DiffOptions{})
+	if len(changes) != 1 || changes[0].Path != "networkHealthState" {
+		t.Fatalf("changes = %+v", changes)
+	}
+}
@@ -0,0 +1,442 @@
+package toon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// HolidayState is the state of a scheduled holiday
+type HolidayState int
+
+// Holiday states
+const (
+	// HolidayScheduled is waiting for the start of the holiday
+	HolidayScheduled HolidayState = iota
+	// HolidayActive has the holiday preset active on the thermostat
+	HolidayActive
+)
+
+var holidayStates = [...]string{
+	"scheduled",
+	"active",
+}
+
+// String() function will return the name of a holiday state
+func (s HolidayState) String() string {
+	return holidayStates[s]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s HolidayState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *HolidayState) UnmarshalText(text []byte) error {
+	for i, name := range holidayStates {
+		if name == string(text) {
+			*s = HolidayState(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown holiday state %q", text)
+}
+
+// Holiday is a vacation period during which the thermostat keeps the holiday temperature
+type Holiday struct {
+	AgreementID string       `json:"agreementId"`
+	Start       time.Time    `json:"start"`
+	End         time.Time    `json:"end"`
+	Temperature float64      `json:"temperature"`
+	State       HolidayState `json:"state"`
+}
+
+// HolidayEventType is the type of a HolidayEvent
+type HolidayEventType int
+
+// Holiday event types
+const (
+	// HolidayStarted is emitted when the holiday preset is activated
+	HolidayStarted HolidayEventType = iota
+	// HolidayEnded is emitted when the weekly program is reinstated after the holiday
+	HolidayEnded
+	// HolidayDisplayOffline is emitted when the holiday could not start or end because the
+	// Toon display is offline, the scheduler will retry after HolidayScheduler.RetryDelay
+	HolidayDisplayOffline
+	// HolidayFailed is emitted when starting or ending the holiday failed, it will be retried
+	HolidayFailed
+	// HolidayCancelled is emitted when the holiday is cancelled
+	HolidayCancelled
+)
+
+var holidayEventTypes = [...]string{
+	"started",
+	"ended",
+	"display offline",
+	"failed",
+	"cancelled",
+}
+
+// String() function will return the name of a holiday event type
+func (t HolidayEventType) String() string {
+	return holidayEventTypes[t]
+}
+
+// HolidayEvent is emitted by the HolidayScheduler when a holiday changes
+type HolidayEvent struct {
+	Type    HolidayEventType
+	Holiday Holiday
+	Err     *ErrorResponse
+}
+
+// HolidayStore persists scheduled holidays so they survive restarts
+type HolidayStore interface {
+	Save(holiday Holiday) error
+	Delete(agreementID string) error
+	List() ([]Holiday, error)
+}
+
+// HolidayScheduler activates the holiday preset at the start of a holiday and reinstates the weekly
+// program when it ends, call Resume after creating a scheduler to pick up holidays from a previous run
+type HolidayScheduler struct {
+	auth   *auth.ToonAuthenticator
+	store  HolidayStore
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	// stopped prevents scheduling transitions after Stop until Resume
+	stopped bool
+	// events are emitted while holding the lock and dispatched to OnEvent after unlocking
+	events []HolidayEvent
+	// DisplayOfflineAfter is the time since LastUpdateFromDisplay after which the display is considered offline
+	DisplayOfflineAfter time.Duration
+	// RetryDelay is the time to wait before retrying when the display is offline or a call failed
+	RetryDelay time.Duration
+	// OnEvent is called when a holiday starts, ends, is cancelled or could not be applied,
+	// it is called after the scheduler is unlocked so it can use the scheduler
+	OnEvent func(event HolidayEvent)
+}
+
+// NewHolidayScheduler creates a new HolidayScheduler which persists holidays in the store
+func NewHolidayScheduler(auth *auth.ToonAuthenticator, store HolidayStore) *HolidayScheduler {
+	return &HolidayScheduler{
+		auth:                auth,
+		store:               store,
+		timers:              map[string]*time.Timer{},
+		DisplayOfflineAfter: 15 * time.Minute,
+		RetryDelay:          5 * time.Minute,
+	}
+}
+
+// Schedule schedules a holiday from start until end with a temperature in degrees Celsius,
+// an existing holiday for the agreement is replaced. After Stop the holiday is only saved
+// and scheduled by Resume.
+func (h *HolidayScheduler) Schedule(agreementID string, start, end time.Time, celsius float64) (*Holiday, *ErrorResponse) {
+	if err := validateTemperature(celsius); err != nil {
+		return nil, newErrorResponse(err, "Invalid temperature")
+	}
+
+	if !end.After(start) || !end.After(time.Now()) {
+		return nil, newErrorResponse(fmt.Errorf("holiday end %v should be after start %v and in the future", end, start), "Invalid holiday")
+	}
+
+	h.mu.Lock()
+	defer h.unlock()
+
+	holiday := Holiday{AgreementID: agreementID, Start: start, End: end, Temperature: celsius, State: HolidayScheduled}
+	if existing, err := h.get(agreementID); err == nil && existing != nil && existing.State == HolidayActive {
+		holiday.State = HolidayActive
+	}
+
+	if err := h.store.Save(holiday); err != nil {
+		return nil, newErrorResponse(err, "Unable to save holiday")
+	}
+
+	h.schedule(holiday)
+	return &holiday, nil
+}
+
+// Get returns the holiday of an agreement or nil when there is none
+func (h *HolidayScheduler) Get(agreementID string) (*Holiday, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.get(agreementID)
+}
+
+// Cancel removes the holiday of an agreement, when the holiday is active the weekly program is reinstated
+func (h *HolidayScheduler) Cancel(agreementID string) *ErrorResponse {
+	h.mu.Lock()
+	defer h.unlock()
+
+	holiday, err := h.get(agreementID)
+	if err != nil {
+		return newErrorResponse(err, "Unable to load holiday")
+	}
+
+	if holiday == nil {
+		return nil
+	}
+
+	if holiday.State == HolidayActive {
+		if errResponse := ResumeProgram(h.auth, agreementID); errResponse != nil {
+			return errResponse
+		}
+	}
+
+	h.stopTimer(agreementID)
+	if err := h.store.Delete(agreementID); err != nil {
+		return newErrorResponse(err, "Unable to delete holiday")
+	}
+
+	h.emit(HolidayEvent{Type: HolidayCancelled, Holiday: *holiday})
+	return nil
+}
+
+// Resume schedules the holidays from the store, holidays which should have started or
+// ended while the process was not running are applied right away, also after Stop
+func (h *HolidayScheduler) Resume() error {
+	holidays, err := h.store.List()
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.unlock()
+
+	h.stopped = false
+	for _, holiday := range holidays {
+		h.schedule(holiday)
+	}
+
+	return nil
+}
+
+// Stop stops all timers and retries, holidays stay in the store and can be resumed later
+func (h *HolidayScheduler) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+	for agreementID := range h.timers {
+		h.stopTimer(agreementID)
+	}
+}
+
+func (h *HolidayScheduler) get(agreementID string) (*Holiday, error) {
+	holidays, err := h.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, holiday := range holidays {
+		if holiday.AgreementID == agreementID {
+			return &holiday, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// schedule sets a timer for the next transition of the holiday, should be called while holding the lock
+func (h *HolidayScheduler) schedule(holiday Holiday) {
+	next := holiday.Start
+	if holiday.State == HolidayActive || !time.Now().Before(holiday.End) {
+		next = holiday.End
+	}
+
+	h.scheduleAfter(holiday.AgreementID, time.Until(next))
+}
+
+// scheduleAfter replaces the timer of an agreement, a timer which fires after it was stopped
+// or replaced does nothing. Should be called while holding the lock.
+func (h *HolidayScheduler) scheduleAfter(agreementID string, after time.Duration) {
+	h.stopTimer(agreementID)
+	if h.stopped {
+		return
+	}
+
+	if after < 0 {
+		after = 0
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(after, func() {
+		h.mu.Lock()
+		defer h.unlock()
+
+		if h.timers[agreementID] != timer {
+			return
+		}
+
+		delete(h.timers, agreementID)
+		h.transition(agreementID)
+	})
+	h.timers[agreementID] = timer
+}
+
+func (h *HolidayScheduler) stopTimer(agreementID string) {
+	if timer, ok := h.timers[agreementID]; ok {
+		timer.Stop()
+		delete(h.timers, agreementID)
+	}
+}
+
+// transition starts or ends the holiday depending on its state and the current time,
+// should be called while holding the lock
+func (h *HolidayScheduler) transition(agreementID string) {
+	holiday, err := h.get(agreementID)
+	if err != nil || holiday == nil {
+		if err != nil {
+			telemetry.Logger().Warn("unable to load toon holiday", "agreement_id", agreementID, "error", err)
+			h.scheduleAfter(agreementID, h.RetryDelay)
+		}
+
+		return
+	}
+
+	ending := holiday.State == HolidayActive || !time.Now().Before(holiday.End)
+	if ending && holiday.State != HolidayActive {
+		// the holiday ended before it could be started, nothing to reinstate
+		h.stopTimer(agreementID)
+		h.store.Delete(agreementID)
+		h.emit(HolidayEvent{Type: HolidayEnded, Holiday: *holiday})
+		return
+	}
+
+	if !ending && time.Now().Before(holiday.Start) {
+		// a timer fired early or the holiday was moved, wait for the start
+		h.schedule(*holiday)
+		return
+	}
+
+	if offline, errResponse := h.displayOffline(agreementID); errResponse != nil || offline {
+		eventType := HolidayDisplayOffline
+		if errResponse != nil {
+			eventType = HolidayFailed
+		}
+
+		telemetry.Logger().Warn("unable to apply toon holiday, retrying later", "agreement_id", agreementID, "event", eventType, "retry_delay", h.RetryDelay)
+		h.emit(HolidayEvent{Type: eventType, Holiday: *holiday, Err: errResponse})
+		h.scheduleAfter(agreementID, h.RetryDelay)
+		return
+	}
+
+	if ending {
+		h.end(*holiday)
+		return
+	}
+
+	h.start(*holiday)
+}
+
+func (h *HolidayScheduler) start(holiday Holiday) {
+	_, errResponse := SetThermostatState(h.auth, holiday.AgreementID, PresetHoliday, holiday.Temperature)
+	if errResponse == nil {
+		errResponse = SetActivePreset(h.auth, holiday.AgreementID, PresetHoliday, OverridePermanent)
+	}
+
+	if errResponse != nil {
+		h.emit(HolidayEvent{Type: HolidayFailed, Holiday: holiday, Err: errResponse})
+		h.scheduleAfter(holiday.AgreementID, h.RetryDelay)
+		return
+	}
+
+	holiday.State = HolidayActive
+	if err := h.store.Save(holiday); err != nil {
+		telemetry.Logger().Warn("unable to save toon holiday", "agreement_id", holiday.AgreementID, "error", err)
+	}
+
+	telemetry.Logger().Info("toon holiday started", "agreement_id", holiday.AgreementID, "until", holiday.End)
+	h.emit(HolidayEvent{Type: HolidayStarted, Holiday: holiday})
+	h.schedule(holiday)
+}
+
+func (h *HolidayScheduler) end(holiday Holiday) {
+	if errResponse := ResumeProgram(h.auth, holiday.AgreementID); errResponse != nil {
+		h.emit(HolidayEvent{Type: HolidayFailed, Holiday: holiday, Err: errResponse})
+		h.scheduleAfter(holiday.AgreementID, h.RetryDelay)
+		return
+	}
+
+	h.stopTimer(holiday.AgreementID)
+	if err := h.store.Delete(holiday.AgreementID); err != nil {
+		telemetry.Logger().Warn("unable to delete toon holiday", "agreement_id", holiday.AgreementID, "error", err)
+	}
+
+	telemetry.Logger().Info("toon holiday ended", "agreement_id", holiday.AgreementID)
+	h.emit(HolidayEvent{Type: HolidayEnded, Holiday: holiday})
+}
+
+// displayOffline checks if the Toon display did not report to the server for DisplayOfflineAfter
+func (h *HolidayScheduler) displayOffline(agreementID string) (bool, *ErrorResponse) {
+	status, errResponse := GetStatus(h.auth, agreementID)
+	if errResponse != nil {
+		return false, errResponse
+	}
+
+	return DisplayOffline(status, h.DisplayOfflineAfter), nil
+}
+
+// emit queues an event for OnEvent, should be called while holding the lock
+func (h *HolidayScheduler) emit(event HolidayEvent) {
+	h.events = append(h.events, event)
+}
+
+// unlock releases the lock and dispatches the emitted events to OnEvent
+func (h *HolidayScheduler) unlock() {
+	events := h.events
+	h.events = nil
+	h.mu.Unlock()
+
+	if h.OnEvent != nil {
+		for _, event := range events {
+			h.OnEvent(event)
+		}
+	}
+}
+
+// DisplayOffline returns true when the Toon display did not send an update to the server
+// for longer than the given duration, the server time of the status is used as current time.
+// A status without LastUpdateFromDisplay is not considered offline as the last update is unknown.
+func DisplayOffline(status *Status, after time.Duration) bool {
+	if status.LastUpdateFromDisplay == 0 {
+		return false
+	}
+
+	now := time.Now()
+	if status.ServerTime != 0 {
+		now = time.UnixMilli(status.ServerTime)
+	}
+
+	return now.Sub(time.UnixMilli(status.LastUpdateFromDisplay)) > after
+}
+
+// FileHolidayStore is a HolidayStore which keeps holidays in a JSON file
+type FileHolidayStore struct {
+	Path  string
+	store fileStore[Holiday]
+}
+
+// NewFileHolidayStore creates a HolidayStore which keeps holidays in the file at path
+func NewFileHolidayStore(path string) *FileHolidayStore {
+	return &FileHolidayStore{Path: path}
+}
+
+// Save implements HolidayStore
+func (s *FileHolidayStore) Save(holiday Holiday) error {
+	return s.store.save(s.Path, holiday.AgreementID, holiday)
+}
+
+// Delete implements HolidayStore
+func (s *FileHolidayStore) Delete(agreementID string) error {
+	return s.store.delete(s.Path, agreementID)
+}
+
+// List implements HolidayStore
+func (s *FileHolidayStore) List() ([]Holiday, error) {
+	return s.store.list(s.Path)
+}
@@ -0,0 +1,182 @@
+package toon
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+type memoryHolidayStore struct {
+	mu       sync.Mutex
+	holidays map[string]Holiday
+}
+
+func (s *memoryHolidayStore) Save(holiday Holiday) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.holidays[holiday.AgreementID] = holiday
+	return nil
+}
+
+func (s *memoryHolidayStore) Delete(agreementID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.holidays, agreementID)
+	return nil
+}
+
+func (s *memoryHolidayStore) List() ([]Holiday, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	holidays := []Holiday{}
+	for _, holiday := range s.holidays {
+		holidays = append(holidays, holiday)
+	}
+
+	return holidays, nil
+}
+
+// newTestHolidayScheduler returns a scheduler talking to a fake Toon, the display is offline while offline is set
+func newTestHolidayScheduler(t *testing.T, offline *atomic.Bool) (*HolidayScheduler, chan HolidayEvent) {
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/toon/v3/1/status":
+			lastUpdate := time.Now()
+			if offline.Load() {
+				lastUpdate = lastUpdate.Add(-time.Hour)
+			}
+
+			fmt.Fprintf(w, `{"serverTime":%d,"lastUpdateFromDisplay":%d}`, time.Now().UnixMilli(), lastUpdate.UnixMilli())
+		case r.URL.Path == "/toon/v3/1/thermostat/states" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"state":[{"id":0,"tempValue":2000},{"id":4,"tempValue":1200}]}`))
+		case r.URL.Path == "/toon/v3/1/thermostat/states", r.URL.Path == "/toon/v3/1/thermostat":
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	scheduler := NewHolidayScheduler(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0), &memoryHolidayStore{holidays: map[string]Holiday{}})
+	scheduler.RetryDelay = 10 * time.Millisecond
+	t.Cleanup(scheduler.Stop)
+
+	events := make(chan HolidayEvent, 10)
+	scheduler.OnEvent = func(event HolidayEvent) {
+		// OnEvent is called without holding the lock, so it can use the scheduler
+		scheduler.Get(event.Holiday.AgreementID)
+		events <- event
+	}
+
+	return scheduler, events
+}
+
+func expectHolidayEvent(t *testing.T, events chan HolidayEvent, want HolidayEventType) HolidayEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.Type != want {
+			t.Fatalf("got %v event, want %v", event.Type, want)
+		}
+
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for %v event", want)
+	}
+
+	return HolidayEvent{}
+}
+
+func TestHolidaySchedulerStartAndEnd(t *testing.T) {
+	scheduler, events := newTestHolidayScheduler(t, &atomic.Bool{})
+
+	if _, err := scheduler.Schedule("1", time.Now().Add(10*time.Millisecond), time.Now().Add(60*time.Millisecond), 12); err != nil {
+		t.Fatalf("Schedule() = %v", err)
+	}
+
+	if event := expectHolidayEvent(t, events, HolidayStarted); event.Holiday.State != HolidayActive {
+		t.Fatalf("started holiday has state %v, want %v", event.Holiday.State, HolidayActive)
+	}
+
+	expectHolidayEvent(t, events, HolidayEnded)
+	if holiday, _ := scheduler.Get("1"); holiday != nil {
+		t.Fatalf("Get() = %+v after the holiday ended, want nil", holiday)
+	}
+}
+
+func TestHolidaySchedulerDisplayOffline(t *testing.T) {
+	offline := &atomic.Bool{}
+	offline.Store(true)
+	scheduler, events := newTestHolidayScheduler(t, offline)
+
+	if _, err := scheduler.Schedule("1", time.Now(), time.Now().Add(time.Hour), 12); err != nil {
+		t.Fatalf("Schedule() = %v", err)
+	}
+
+	// the holiday is retried after RetryDelay until the display is back online
+	expectHolidayEvent(t, events, HolidayDisplayOffline)
+	offline.Store(false)
+	for event := range events {
+		if event.Type == HolidayStarted {
+			break
+		}
+
+		if event.Type != HolidayDisplayOffline {
+			t.Fatalf("got %v event, want %v or %v", event.Type, HolidayDisplayOffline, HolidayStarted)
+		}
+	}
+
+	if err := scheduler.Cancel("1"); err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+
+	expectHolidayEvent(t, events, HolidayCancelled)
+}
+
+func TestHolidaySchedulerStop(t *testing.T) {
+	scheduler, events := newTestHolidayScheduler(t, &atomic.Bool{})
+
+	if _, err := scheduler.Schedule("1", time.Now().Add(20*time.Millisecond), time.Now().Add(time.Hour), 12); err != nil {
+		t.Fatalf("Schedule() = %v", err)
+	}
+
+	scheduler.Stop()
+	time.Sleep(60 * time.Millisecond)
+	select {
+	case event := <-events:
+		t.Fatalf("got %v event after Stop", event.Type)
+	default:
+	}
+
+	// Resume picks up the stored holiday, which should have started by now
+	if err := scheduler.Resume(); err != nil {
+		t.Fatalf("Resume() = %v", err)
+	}
+
+	expectHolidayEvent(t, events, HolidayStarted)
+}
+
+func TestDisplayOffline(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		lastUpdate int64
+		want       bool
+	}{
+		{name: "recent update", lastUpdate: now.Add(-time.Minute).UnixMilli(), want: false},
+		{name: "old update", lastUpdate: now.Add(-time.Hour).UnixMilli(), want: true},
+		{name: "unknown last update", lastUpdate: 0, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := &Status{ServerTime: now.UnixMilli(), LastUpdateFromDisplay: test.lastUpdate}
+			if got := DisplayOffline(status, 15*time.Minute); got != test.want {
+				t.Fatalf("DisplayOffline() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,13 @@
 package toon
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Interval int
 
 const (
@@ -30,6 +38,32 @@ func (i Interval) String() string {
 // ErrorResponse description
 type ErrorResponse struct {
 	Fault Fault `json:"fault"`
+	// Err contains the underlying error when the request failed on the client side,
+	// use errors.Is to check for errors such as ErrCircuitOpen
+	Err error `json:"-"`
+}
+
+// Error returns the fault string and error code
+func (e *ErrorResponse) Error() string {
+	if len(e.Fault.Detail.Errorcode) == 0 {
+		return e.Fault.Faultstring
+	}
+
+	return fmt.Sprintf("%s: %s", e.Fault.Detail.Errorcode, e.Fault.Faultstring)
+}
+
+// Unwrap returns the underlying error
+func (e *ErrorResponse) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+
+	return e.Err
+}
+
+// newErrorResponse creates an ErrorResponse for an error which occurred on the client side
+func newErrorResponse(err error, errorcode string) *ErrorResponse {
+	return &ErrorResponse{Fault: Fault{Faultstring: fmt.Sprintf("%v", err), Detail: FaultDetail{Errorcode: errorcode}}, Err: err}
 }
 
 // Fault description
@@ -109,13 +143,125 @@ type ThermostatStates struct {
 	States []ThermostatState `json:"state"`
 }
 
-// ThermostatState description
+// ThermostatState description, TempValue is in hundredths of a degree Celsius
 type ThermostatState struct {
 	ID        int `json:"id"`
 	TempValue int `json:"tempValue"`
 	Dhq       int `json:"dhw"`
 }
 
+// Preset returns the preset of the thermostat state
+func (s ThermostatState) Preset() Preset {
+	return Preset(s.ID)
+}
+
+// Celsius returns the temperature of the thermostat state in degrees Celsius
+func (s ThermostatState) Celsius() float64 {
+	return toCelsius(s.TempValue)
+}
+
+// Get returns the thermostat state of a preset
+func (s ThermostatStates) Get(preset Preset) (ThermostatState, bool) {
+	for _, state := range s.States {
+		if state.Preset() == preset {
+			return state, true
+		}
+	}
+
+	return ThermostatState{}, false
+}
+
+// Preset is a thermostat state such as Comfort or Away, used as id of a ThermostatState
+// and as ActiveState in ThermostatInfo
+type Preset int
+
+// Thermostat presets
+const (
+	PresetComfort Preset = iota
+	PresetHome
+	PresetSleep
+	PresetAway
+	PresetHoliday
+)
+
+// PresetNone is the active state when a manual setpoint is used
+const PresetNone Preset = -1
+
+var presets = [...]string{
+	"comfort",
+	"home",
+	"sleep",
+	"away",
+	"holiday",
+}
+
+// PresetEnum contains all presets which can be activated
+var PresetEnum = []Preset{PresetComfort, PresetHome, PresetSleep, PresetAway, PresetHoliday}
+
+// String() function will return the name of a preset
+func (p Preset) String() string {
+	if p < 0 || int(p) >= len(presets) {
+		return "none"
+	}
+
+	return presets[p]
+}
+
+// OverrideMode defines how long a preset or setpoint set by the SDK stays active
+type OverrideMode int
+
+// Override modes
+const (
+	// OverrideTemporary stays active until the next change in the weekly program
+	OverrideTemporary OverrideMode = iota
+	// OverridePermanent switches the weekly program off
+	OverridePermanent
+)
+
+// programState returns the program state for an override
+func (m OverrideMode) programState() ProgramState {
+	if m == OverridePermanent {
+		return ProgramOff
+	}
+
+	return ProgramTemporaryOverride
+}
+
+// Temperature limits of the Toon thermostat in degrees Celsius
+const (
+	MinTemperature = 6.0
+	MaxTemperature = 30.0
+)
+
+// ErrTemperatureOutOfRange is returned when a temperature outside MinTemperature and MaxTemperature is supplied
+var ErrTemperatureOutOfRange = fmt.Errorf("temperature out of range, should be between %v and %v degrees Celsius", MinTemperature, MaxTemperature)
+
+// thermostatUpdate is the payload to update the thermostat, nil fields are not send
+type thermostatUpdate struct {
+	CurrentSetpoint *int          `json:"currentSetpoint,omitempty"`
+	ProgramState    *ProgramState `json:"programState,omitempty"`
+	ActiveState     *int          `json:"activeState,omitempty"`
+}
+
+// toCelsius converts a Toon temperature in hundredths of a degree to degrees Celsius
+func toCelsius(value int) float64 {
+	return float64(value) / 100
+}
+
+// fromCelsius converts degrees Celsius to a Toon temperature in hundredths of a degree
+func fromCelsius(celsius float64) int {
+	return int(math.Round(celsius * 100))
+}
+
+// validateTemperature checks if a temperature is within the limits of the thermostat
+func validateTemperature(celsius float64) error {
+	if math.IsNaN(celsius) || celsius < MinTemperature || celsius > MaxTemperature {
+		return fmt.Errorf("%w: %v", ErrTemperatureOutOfRange, celsius)
+	}
+
+	return nil
+}
+
 // ThermostatInfo description
 type ThermostatInfo struct {
 	CurrentSetpoint        int    `json:"currentSetpoint"`
@@ -135,10 +281,161 @@ type ThermostatInfo struct {
 	HaveOTBoiler           int    `json:"haveOTBoiler"`
 }
 
+// ProgramState is the state of the weekly program of the thermostat
+type ProgramState int
+
+// Program states as used in ThermostatInfo.ProgramState
+const (
+	// ProgramOff has the weekly program switched off, the setpoint or preset is kept permanently
+	ProgramOff ProgramState = iota
+	// ProgramOn follows the weekly program
+	ProgramOn
+	// ProgramTemporaryOverride keeps a setpoint or preset until the next change in the weekly program
+	ProgramTemporaryOverride
+)
+
+var programStates = [...]string{
+	"off",
+	"on",
+	"temporary override",
+}
+
+// String() function will return the name of a program state
+func (s ProgramState) String() string {
+	if s < 0 || int(s) >= len(programStates) {
+		return "unknown"
+	}
+
+	return programStates[s]
+}
+
+// BurnerState is what the boiler burner is currently doing
+type BurnerState int
+
+// Burner states as used in ThermostatInfo.BurnerInfo
+const (
+	BurnerIdle BurnerState = iota
+	// BurnerCentralHeating heats the house
+	BurnerCentralHeating
+	// BurnerHotWater heats domestic hot water
+	BurnerHotWater
+	// BurnerPreheat heats the house in advance of the next program change
+	BurnerPreheat
+	// BurnerUnknown is returned when the burner info can not be parsed
+	BurnerUnknown BurnerState = -1
+)
+
+var burnerStates = [...]string{
+	"idle",
+	"central heating",
+	"hot water",
+	"preheat",
+}
+
+// String() function will return the name of a burner state
+func (s BurnerState) String() string {
+	if s < 0 || int(s) >= len(burnerStates) {
+		return "unknown"
+	}
+
+	return burnerStates[s]
+}
+
+// NoErrorFound is the value of ThermostatInfo.ErrorFound when there is no boiler error
+const NoErrorFound = 255
+
+// Program returns the state of the weekly program
+func (t ThermostatInfo) Program() ProgramState {
+	return ProgramState(t.ProgramState)
+}
+
+// Active returns the active preset, PresetNone when a manual setpoint is used
+func (t ThermostatInfo) Active() Preset {
+	return Preset(t.ActiveState)
+}
+
+// Next returns the preset which becomes active at the next program change
+func (t ThermostatInfo) Next() Preset {
+	return Preset(t.NextState)
+}
+
+// Burner returns what the boiler burner is currently doing
+func (t ThermostatInfo) Burner() BurnerState {
+	state, err := strconv.Atoi(t.BurnerInfo)
+	if err != nil || state < 0 || state >= len(burnerStates) {
+		return BurnerUnknown
+	}
+
+	return BurnerState(state)
+}
+
+// IsHeating returns true when the boiler is heating the house, including preheating
+func (t ThermostatInfo) IsHeating() bool {
+	burner := t.Burner()
+	return burner == BurnerCentralHeating || burner == BurnerPreheat
+}
+
+// IsOverride returns true when the setpoint is not set by the weekly program
+func (t ThermostatInfo) IsOverride() bool {
+	return t.Program() != ProgramOn || t.Active() == PresetNone
+}
+
+// SetpointCelsius returns the current setpoint in degrees Celsius
+func (t ThermostatInfo) SetpointCelsius() float64 {
+	return toCelsius(t.CurrentSetpoint)
+}
+
+// DisplayTempCelsius returns the room temperature shown on the display in degrees Celsius
+func (t ThermostatInfo) DisplayTempCelsius() float64 {
+	return toCelsius(t.CurrentDisplayTemp)
+}
+
+// NextSetpointCelsius returns the setpoint after the next program change in degrees Celsius
+func (t ThermostatInfo) NextSetpointCelsius() float64 {
+	return toCelsius(t.NextSetpoint)
+}
+
+// NextChange returns the time of the next program change, the zero time when there is none
+func (t ThermostatInfo) NextChange() time.Time {
+	if t.NextTime <= 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(t.NextTime), 0)
+}
+
+// HasError returns true when the thermostat reports a boiler error
+func (t ThermostatInfo) HasError() bool {
+	return t.ErrorFound != NoErrorFound
+}
+
+// IsBoilerModuleConnected returns true when the boiler module is connected to the thermostat
+func (t ThermostatInfo) IsBoilerModuleConnected() bool {
+	return t.BoilerModuleConnected == 1
+}
+
+// HasOpenThermBoiler returns true when the boiler is connected using OpenTherm
+func (t ThermostatInfo) HasOpenThermBoiler() bool {
+	return t.HaveOTBoiler == 1
+}
+
+// HasOpenThermCommError returns true when communication with the OpenTherm boiler fails
+func (t ThermostatInfo) HasOpenThermCommError() bool {
+	return len(t.OtCommError) != 0 && t.OtCommError != "0"
+}
+
+// CurrentTemperature contains the room temperature and setpoint of the thermostat in degrees Celsius
+type CurrentTemperature struct {
+	Room     float64 `json:"room"`
+	Setpoint float64 `json:"setpoint"`
+	// Override is true when the setpoint is set manually instead of by the weekly program
+	Override bool `json:"override"`
+}
+
 // SmokeDetectors description
 type SmokeDetectors struct {
-	// Have no smoke detector myself yet and unable to find which info returns
-	Devices []interface{} `json:"device"`
+	// The schema is not documented, SmokeDetector decodes the known fields tolerantly and keeps the rest
+	Devices []SmokeDetector `json:"device"`
 }
 
 // DeviceConfigInfo description
@@ -163,6 +460,75 @@ type DeviceConfig struct {
 	Zwuuid            string `json:"zwuuid"`
 }
 
+// DeviceConfigs contains the configuration of all smart plugs and lights
+type DeviceConfigs []DeviceConfig
+
+// Get returns the configuration of a device by its DevUUID
+func (d DeviceConfigs) Get(devUUID string) (DeviceConfig, bool) {
+	for _, config := range d {
+		if config.DevUUID == devUUID {
+			return config, true
+		}
+	}
+
+	return DeviceConfig{}, false
+}
+
+// InSwitchAll returns the devices which are in the switch all group
+func (d DeviceConfigs) InSwitchAll() DeviceConfigs {
+	devices := DeviceConfigs{}
+	for _, config := range d {
+		if config.IsInSwitchAll() {
+			devices = append(devices, config)
+		}
+	}
+
+	return devices
+}
+
+// IsOn returns true when the device is switched on
+func (d DeviceConfig) IsOn() bool {
+	return parseFlag(d.CurrentState)
+}
+
+// IsSwitchLocked returns true when the device can not be switched
+func (d DeviceConfig) IsSwitchLocked() bool {
+	return parseFlag(d.SwitchLocked)
+}
+
+// IsInSwitchAll returns true when the device is switched by the switch all function
+func (d DeviceConfig) IsInSwitchAll() bool {
+	return d.InSwitchAll == 1
+}
+
+// IsInSwitchSchedule returns true when the device is switched by its schedule
+func (d DeviceConfig) IsInSwitchSchedule() bool {
+	return d.InSwitchSchedule == 1
+}
+
+// parseFlag parses a boolean value which Toon sends as string
+func parseFlag(value string) bool {
+	return value == "1" || strings.EqualFold(value, "true")
+}
+
+// formatFlag formats a boolean value as string flag
+func formatFlag(value bool) string {
+	if value {
+		return "1"
+	}
+
+	return "0"
+}
+
+// formatIntFlag formats a boolean value as int flag
+func formatIntFlag(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
 // DeviceStatusInfo description
 type DeviceStatusInfo struct {
 	Status           []DeviceStatus   `json:"device"`
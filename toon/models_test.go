@@ -0,0 +1,112 @@
+package toon
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func loadThermostatInfo(t *testing.T) ThermostatInfo {
+	t.Helper()
+	data, err := os.ReadFile("testdata/thermostat_info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := ThermostatInfo{}
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	return info
+}
+
+func TestThermostatInfoFixture(t *testing.T) {
+	info := loadThermostatInfo(t)
+
+	if got := info.Program(); got != ProgramOn {
+		t.Errorf("Program() = %v, want %v", got, ProgramOn)
+	}
+
+	if got := info.Active(); got != PresetHome {
+		t.Errorf("Active() = %v, want %v", got, PresetHome)
+	}
+
+	if got := info.Next(); got != PresetSleep {
+		t.Errorf("Next() = %v, want %v", got, PresetSleep)
+	}
+
+	if got := info.Burner(); got != BurnerCentralHeating {
+		t.Errorf("Burner() = %v, want %v", got, BurnerCentralHeating)
+	}
+
+	if !info.IsHeating() || info.IsOverride() || info.HasError() {
+		t.Errorf("IsHeating() = %v, IsOverride() = %v, HasError() = %v", info.IsHeating(), info.IsOverride(), info.HasError())
+	}
+
+	if info.SetpointCelsius() != 20.5 || info.DisplayTempCelsius() != 21.18 || info.NextSetpointCelsius() != 15 {
+		t.Errorf("setpoint %v, display %v, next setpoint %v", info.SetpointCelsius(), info.DisplayTempCelsius(), info.NextSetpointCelsius())
+	}
+
+	if got, want := info.NextChange(), time.Unix(1538460000, 0); !got.Equal(want) {
+		t.Errorf("NextChange() = %v, want %v", got, want)
+	}
+
+	if !info.IsBoilerModuleConnected() || !info.HasOpenThermBoiler() || info.HasOpenThermCommError() {
+		t.Errorf("boiler module %v, OpenTherm %v, comm error %v", info.IsBoilerModuleConnected(), info.HasOpenThermBoiler(), info.HasOpenThermCommError())
+	}
+}
+
+func TestThermostatInfoBurner(t *testing.T) {
+	tests := []struct {
+		burnerInfo string
+		want       BurnerState
+		heating    bool
+	}{
+		{"0", BurnerIdle, false},
+		{"1", BurnerCentralHeating, true},
+		{"2", BurnerHotWater, false},
+		{"3", BurnerPreheat, true},
+		{"4", BurnerUnknown, false},
+		{"-1", BurnerUnknown, false},
+		{"", BurnerUnknown, false},
+		{"on", BurnerUnknown, false},
+	}
+
+	for _, test := range tests {
+		info := ThermostatInfo{BurnerInfo: test.burnerInfo}
+		if got := info.Burner(); got != test.want {
+			t.Errorf("Burner(%q) = %v, want %v", test.burnerInfo, got, test.want)
+		}
+
+		if got := info.IsHeating(); got != test.heating {
+			t.Errorf("IsHeating(%q) = %v, want %v", test.burnerInfo, got, test.heating)
+		}
+	}
+}
+
+func TestThermostatInfoHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		info  ThermostatInfo
+		check func(info ThermostatInfo) bool
+	}{
+		{"no next change", ThermostatInfo{NextTime: 0}, func(i ThermostatInfo) bool { return i.NextChange().IsZero() }},
+		{"temporary override", ThermostatInfo{ProgramState: 2, ActiveState: 0}, func(i ThermostatInfo) bool { return i.Program() == ProgramTemporaryOverride && i.IsOverride() }},
+		{"manual setpoint", ThermostatInfo{ProgramState: 1, ActiveState: -1}, func(i ThermostatInfo) bool { return i.Active() == PresetNone && i.IsOverride() }},
+		{"unknown program state", ThermostatInfo{ProgramState: 7}, func(i ThermostatInfo) bool { return i.Program().String() == "unknown" }},
+		{"boiler error", ThermostatInfo{ErrorFound: 3}, func(i ThermostatInfo) bool { return i.HasError() }},
+		{"comm error", ThermostatInfo{OtCommError: "1"}, func(i ThermostatInfo) bool { return i.HasOpenThermCommError() }},
+		{"no comm error", ThermostatInfo{OtCommError: ""}, func(i ThermostatInfo) bool { return !i.HasOpenThermCommError() }},
+		{"setpoint", ThermostatInfo{CurrentSetpoint: 1850}, func(i ThermostatInfo) bool { return i.SetpointCelsius() == 18.5 }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.check(test.info) {
+				t.Errorf("unexpected result for %+v", test.info)
+			}
+		})
+	}
+}
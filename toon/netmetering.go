@@ -0,0 +1,107 @@
+package toon
+
+import "math"
+
+// ProductionAndDelivery contains the electricity consumed from the grid, delivered to the grid and produced
+// by solar panels per interval, only the slice of the requested interval is filled
+type ProductionAndDelivery struct {
+	Hours  []ProductionAndDeliveryValue `json:"hours"`
+	Days   []ProductionAndDeliveryValue `json:"days"`
+	Weeks  []ProductionAndDeliveryValue `json:"weeks"`
+	Months []ProductionAndDeliveryValue `json:"months"`
+	Years  []ProductionAndDeliveryValue `json:"years"`
+}
+
+// ProductionAndDeliveryValue contains the electricity of one interval split by peak and off-peak tariff
+type ProductionAndDeliveryValue struct {
+	Timestamp          int64   `json:"timestamp"`
+	Unit               string  `json:"unit"`
+	PeakConsumption    float64 `json:"peakConsumption"`
+	OffPeakConsumption float64 `json:"offPeakConsumption"`
+	PeakDelivery       float64 `json:"peakDelivery"`
+	OffPeakDelivery    float64 `json:"offPeakDelivery"`
+	PeakProduction     float64 `json:"peakProduction"`
+	OffPeakProduction  float64 `json:"offPeakProduction"`
+}
+
+// Values returns the values of the interval which is filled
+func (p ProductionAndDelivery) Values() []ProductionAndDeliveryValue {
+	for _, values := range [][]ProductionAndDeliveryValue{p.Hours, p.Days, p.Weeks, p.Months, p.Years} {
+		if len(values) > 0 {
+			return values
+		}
+	}
+
+	return []ProductionAndDeliveryValue{}
+}
+
+// PeakSelfConsumption returns the produced electricity which was used directly during peak tariff
+func (v ProductionAndDeliveryValue) PeakSelfConsumption() float64 {
+	return math.Max(0, v.PeakProduction-v.PeakDelivery)
+}
+
+// OffPeakSelfConsumption returns the produced electricity which was used directly during off-peak tariff
+func (v ProductionAndDeliveryValue) OffPeakSelfConsumption() float64 {
+	return math.Max(0, v.OffPeakProduction-v.OffPeakDelivery)
+}
+
+// NetMeteringBalance is the balance of consumed and delivered electricity over a period, as used
+// for net metering (salderen) where delivered electricity is subtracted from consumed electricity
+type NetMeteringBalance struct {
+	Unit                string  `json:"unit"`
+	ConsumedPeak        float64 `json:"consumedPeak"`
+	ConsumedOffPeak     float64 `json:"consumedOffPeak"`
+	DeliveredPeak       float64 `json:"deliveredPeak"`
+	DeliveredOffPeak    float64 `json:"deliveredOffPeak"`
+	SelfConsumedPeak    float64 `json:"selfConsumedPeak"`
+	SelfConsumedOffPeak float64 `json:"selfConsumedOffPeak"`
+}
+
+// Consumed returns the total electricity consumed from the grid
+func (b NetMeteringBalance) Consumed() float64 {
+	return b.ConsumedPeak + b.ConsumedOffPeak
+}
+
+// Delivered returns the total electricity delivered to the grid
+func (b NetMeteringBalance) Delivered() float64 {
+	return b.DeliveredPeak + b.DeliveredOffPeak
+}
+
+// SelfConsumed returns the total produced electricity which was used directly
+func (b NetMeteringBalance) SelfConsumed() float64 {
+	return b.SelfConsumedPeak + b.SelfConsumedOffPeak
+}
+
+// Net returns the consumed minus the delivered electricity, negative when more was delivered than consumed
+func (b NetMeteringBalance) Net() float64 {
+	return b.Consumed() - b.Delivered()
+}
+
+// NetPeak returns the consumed minus the delivered electricity during peak tariff
+func (b NetMeteringBalance) NetPeak() float64 {
+	return b.ConsumedPeak - b.DeliveredPeak
+}
+
+// NetOffPeak returns the consumed minus the delivered electricity during off-peak tariff
+func (b NetMeteringBalance) NetOffPeak() float64 {
+	return b.ConsumedOffPeak - b.DeliveredOffPeak
+}
+
+// Balance sums all values of the filled interval into a net metering balance
+func (p ProductionAndDelivery) Balance() NetMeteringBalance {
+	balance := NetMeteringBalance{}
+	for _, v := range p.Values() {
+		if len(balance.Unit) == 0 {
+			balance.Unit = v.Unit
+		}
+
+		balance.ConsumedPeak += v.PeakConsumption
+		balance.ConsumedOffPeak += v.OffPeakConsumption
+		balance.DeliveredPeak += v.PeakDelivery
+		balance.DeliveredOffPeak += v.OffPeakDelivery
+		balance.SelfConsumedPeak += v.PeakSelfConsumption()
+		balance.SelfConsumedOffPeak += v.OffPeakSelfConsumption()
+	}
+
+	return balance
+}
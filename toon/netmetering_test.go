@@ -0,0 +1,91 @@
+package toon
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+func loadProductionAndDelivery(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/production_delivery.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestProductionAndDeliverySchema(t *testing.T) {
+	// every key of the response should map to a field, a renamed field would silently read as 0
+	decoder := json.NewDecoder(bytes.NewReader(loadProductionAndDelivery(t)))
+	decoder.DisallowUnknownFields()
+
+	data := ProductionAndDelivery{}
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+
+	want := ProductionAndDeliveryValue{
+		Timestamp:          1719784800000,
+		Unit:               "Wh",
+		PeakConsumption:    4200,
+		OffPeakConsumption: 3100,
+		PeakDelivery:       6500,
+		PeakProduction:     9800,
+	}
+	if values := data.Values(); len(values) != 2 || values[0] != want {
+		t.Fatalf("Values() = %+v, want %+v first", values, want)
+	}
+}
+
+func TestNetMeteringBalance(t *testing.T) {
+	recordRequests(t, map[string]string{"/toon/v3/1/production/electricity/delivery": string(loadProductionAndDelivery(t))})
+
+	data, err := GetElectricityProductionAndDelivery(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0), "1", 0, 0, IntervalDays)
+	if err != nil {
+		t.Fatalf("GetElectricityProductionAndDelivery() = %v", err)
+	}
+
+	balance := data.Balance()
+	want := NetMeteringBalance{
+		Unit:                "Wh",
+		ConsumedPeak:        9300,
+		ConsumedOffPeak:     6000,
+		DeliveredPeak:       8800,
+		DeliveredOffPeak:    400,
+		SelfConsumedPeak:    3300 + 1800,
+		SelfConsumedOffPeak: 0,
+	}
+	if balance != want {
+		t.Fatalf("Balance() = %+v, want %+v", balance, want)
+	}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "consumed", got: balance.Consumed(), want: 15300},
+		{name: "delivered", got: balance.Delivered(), want: 9200},
+		{name: "self consumed", got: balance.SelfConsumed(), want: 5100},
+		{name: "net", got: balance.Net(), want: 6100},
+		{name: "net peak", got: balance.NetPeak(), want: 500},
+		{name: "net off-peak", got: balance.NetOffPeak(), want: 5600},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%v = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestNetMeteringBalanceEmpty(t *testing.T) {
+	if balance := (ProductionAndDelivery{}).Balance(); balance != (NetMeteringBalance{}) {
+		t.Fatalf("Balance() = %+v, want the zero balance", balance)
+	}
+}
@@ -0,0 +1,350 @@
+package toon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxProgramBlocksPerDay is the maximum number of program blocks per day supported by the Toon display, a block
+// which continues the last block of the previous day is part of the same Toon entry and is not counted
+const MaxProgramBlocksPerDay = 6
+
+// Errors returned when validating a WeekProgram, check with errors.Is
+var (
+	ErrProgramInvalidBlock  = errors.New("invalid program block")
+	ErrProgramOverlap       = errors.New("program blocks overlap")
+	ErrProgramGap           = errors.New("gap between program blocks")
+	ErrProgramTooManyBlocks = fmt.Errorf("more than %v program blocks on a day", MaxProgramBlocksPerDay)
+)
+
+var errInvalidClockTimeValue = errors.New("invalid clock time")
+
+// ClockTime is a time of day in minutes after midnight, it is marshalled as "15:04"
+type ClockTime int
+
+// EndOfDay is the end of the last block of a day, marshalled as "24:00"
+const EndOfDay ClockTime = 24 * 60
+
+// NewClockTime creates a ClockTime from an hour and minute
+func NewClockTime(hour, minute int) ClockTime {
+	return ClockTime(hour*60 + minute)
+}
+
+// ParseClockTime parses a time of day formatted as "15:04", "24:00" is accepted as EndOfDay
+func ParseClockTime(value string) (ClockTime, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || len(value) != 5 {
+		return 0, fmt.Errorf("%w: %q", errInvalidClockTimeValue, value)
+	}
+
+	t := NewClockTime(hour, minute)
+	if hour < 0 || minute < 0 || minute > 59 || t > EndOfDay {
+		return 0, fmt.Errorf("%w: %q", errInvalidClockTimeValue, value)
+	}
+
+	return t, nil
+}
+
+// Hour returns the hour of the clock time
+func (t ClockTime) Hour() int {
+	return int(t) / 60
+}
+
+// Minute returns the minute of the clock time
+func (t ClockTime) Minute() int {
+	return int(t) % 60
+}
+
+// String() function will return the clock time formatted as "15:04"
+func (t ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", t.Hour(), t.Minute())
+}
+
+// MarshalJSON implements json.Marshaler
+func (t ClockTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *ClockTime) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := ParseClockTime(value)
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+	return nil
+}
+
+// ProgramBlock is a part of a day in the weekly program during which a preset is active,
+// Start is inclusive and End is exclusive
+type ProgramBlock struct {
+	Day    time.Weekday `json:"day"`
+	Start  ClockTime    `json:"start"`
+	End    ClockTime    `json:"end"`
+	Preset Preset       `json:"preset"`
+}
+
+// WeekProgram is the weekly heating schedule of the thermostat, every day should be covered
+// by blocks from 00:00 until 24:00 without overlaps or gaps. The JSON representation is
+// always ordered from Monday to Sunday so it can be compared or diffed.
+type WeekProgram struct {
+	Blocks []ProgramBlock `json:"blocks"`
+}
+
+// weekOrder returns the position of a day in a Toon week, which starts on Monday
+func weekOrder(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// Sort orders the blocks by day, Monday first, and start time
+func (p *WeekProgram) Sort() {
+	sort.SliceStable(p.Blocks, func(i, j int) bool {
+		a, b := p.Blocks[i], p.Blocks[j]
+		if a.Day != b.Day {
+			return weekOrder(a.Day) < weekOrder(b.Day)
+		}
+
+		return a.Start < b.Start
+	})
+}
+
+// Day returns the blocks of a day ordered by start time
+func (p WeekProgram) Day(day time.Weekday) []ProgramBlock {
+	blocks := []ProgramBlock{}
+	for _, b := range p.Blocks {
+		if b.Day == day {
+			blocks = append(blocks, b)
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	return blocks
+}
+
+// At returns the preset which is active on a day and time
+func (p WeekProgram) At(day time.Weekday, t ClockTime) (Preset, bool) {
+	for _, b := range p.Blocks {
+		if b.Day == day && t >= b.Start && t < b.End {
+			return b.Preset, true
+		}
+	}
+
+	return PresetNone, false
+}
+
+// Validate checks the program for invalid, overlapping and gapped blocks and the
+// maximum number of blocks per day, all problems are returned joined in one error
+func (p WeekProgram) Validate() error {
+	var errs []error
+	for d := 0; d < 7; d++ {
+		day := time.Weekday(d)
+		blocks := p.Day(day)
+		entries := 0
+		for _, b := range blocks {
+			if !p.continues(b) {
+				entries++
+			}
+		}
+
+		if entries > MaxProgramBlocksPerDay {
+			errs = append(errs, fmt.Errorf("%w: %v has %v blocks", ErrProgramTooManyBlocks, day, entries))
+		}
+
+		next := ClockTime(0)
+		for _, b := range blocks {
+			if b.Start < 0 || b.End > EndOfDay || b.Start >= b.End {
+				errs = append(errs, fmt.Errorf("%w: %v %v-%v", ErrProgramInvalidBlock, day, b.Start, b.End))
+			}
+
+			if b.Preset < PresetComfort || b.Preset > PresetAway {
+				errs = append(errs, fmt.Errorf("%w: %v %v-%v has preset %v", ErrProgramInvalidBlock, day, b.Start, b.End, b.Preset))
+			}
+
+			if b.Start < next {
+				errs = append(errs, fmt.Errorf("%w: %v %v-%v starts before %v", ErrProgramOverlap, day, b.Start, b.End, next))
+			} else if b.Start > next {
+				errs = append(errs, fmt.Errorf("%w: %v %v-%v", ErrProgramGap, day, next, b.Start))
+			}
+
+			if b.End > next {
+				next = b.End
+			}
+		}
+
+		if next < EndOfDay {
+			errs = append(errs, fmt.Errorf("%w: %v %v-%v", ErrProgramGap, day, next, EndOfDay))
+		}
+	}
+
+	for _, b := range p.Blocks {
+		if b.Day < time.Sunday || b.Day > time.Saturday {
+			errs = append(errs, fmt.Errorf("%w: unknown day %v", ErrProgramInvalidBlock, int(b.Day)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// continues returns true when a block continues the last block of the previous day with the same
+// preset, the Toon API stores both as one entry which spans midnight
+func (p WeekProgram) continues(b ProgramBlock) bool {
+	if b.Start != 0 {
+		return false
+	}
+
+	previous := p.Day((b.Day + 6) % 7)
+	if len(previous) == 0 {
+		return false
+	}
+
+	last := previous[len(previous)-1]
+	return last.End == EndOfDay && last.Preset == b.Preset
+}
+
+// Equal returns true when both programs contain the same blocks, the order is ignored
+func (p WeekProgram) Equal(other WeekProgram) bool {
+	a, _ := json.Marshal(p)
+	b, _ := json.Marshal(other)
+	return string(a) == string(b)
+}
+
+// MarshalJSON implements json.Marshaler, the blocks are sorted so equal
+// programs always have the same JSON representation
+func (p WeekProgram) MarshalJSON() ([]byte, error) {
+	sorted := WeekProgram{Blocks: append([]ProgramBlock{}, p.Blocks...)}
+	sorted.Sort()
+
+	type weekProgram WeekProgram
+	return json.Marshal(weekProgram(sorted))
+}
+
+// errUnexpectedPrograms is returned when the thermostat programs payload does not have the expected shape
+var errUnexpectedPrograms = errors.New("unexpected thermostat programs payload")
+
+// minutesPerDay and minutesPerWeek are used to convert program entries which span midnight
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// thermostatPrograms is the payload of the thermostat programs endpoint, an entry can span
+// midnight, e.g. from Sunday 23:00 until Monday 06:30
+type thermostatPrograms struct {
+	Programs *[]programEntry `json:"programs"`
+}
+
+// programEntry is a program block as used by the Toon API, days start at 0 for Sunday
+type programEntry struct {
+	StartDayOfWeek int `json:"startDayOfWeek"`
+	StartHour      int `json:"startHour"`
+	StartMin       int `json:"startMin"`
+	EndDayOfWeek   int `json:"endDayOfWeek"`
+	EndHour        int `json:"endHour"`
+	EndMin         int `json:"endMin"`
+	TargetState    int `json:"targetState"`
+}
+
+// weekProgram converts the payload to a WeekProgram, entries which span midnight are split into a block per day
+func (p thermostatPrograms) weekProgram() (*WeekProgram, error) {
+	if p.Programs == nil {
+		return nil, fmt.Errorf("%w: missing programs", errUnexpectedPrograms)
+	}
+
+	program := &WeekProgram{Blocks: []ProgramBlock{}}
+	for _, entry := range *p.Programs {
+		start, err := entry.minuteOfWeek(entry.StartDayOfWeek, entry.StartHour, entry.StartMin)
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := entry.minuteOfWeek(entry.EndDayOfWeek, entry.EndHour, entry.EndMin)
+		if err != nil {
+			return nil, err
+		}
+
+		if end <= start {
+			end += minutesPerWeek
+		}
+
+		for t := start; t < end; {
+			dayStart := t - t%minutesPerDay
+			blockEnd := min(end, dayStart+minutesPerDay)
+			program.Blocks = append(program.Blocks, ProgramBlock{
+				Day:    time.Weekday((t / minutesPerDay) % 7),
+				Start:  ClockTime(t - dayStart),
+				End:    ClockTime(blockEnd - dayStart),
+				Preset: Preset(entry.TargetState),
+			})
+			t = blockEnd
+		}
+	}
+
+	program.Sort()
+	return program, nil
+}
+
+func (e programEntry) minuteOfWeek(day, hour, minute int) (int, error) {
+	if day < 0 || day > 6 || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("%w: invalid entry %+v", errUnexpectedPrograms, e)
+	}
+
+	return day*minutesPerDay + hour*60 + minute, nil
+}
+
+// programSpan is a program block as minutes of the week, Sunday 00:00 is 0
+type programSpan struct {
+	start, end int
+	preset     Preset
+}
+
+// newThermostatPrograms converts a WeekProgram to the payload of the thermostat programs endpoint, blocks which
+// continue at midnight with the same preset are merged back into one entry, the reverse of weekProgram
+func newThermostatPrograms(program WeekProgram) thermostatPrograms {
+	spans := make([]programSpan, len(program.Blocks))
+	for i, b := range program.Blocks {
+		dayStart := int(b.Day) * minutesPerDay
+		spans[i] = programSpan{start: dayStart + int(b.Start), end: dayStart + int(b.End), preset: b.Preset}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	merged := []programSpan{}
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.start%minutesPerDay == 0 && merged[n-1].end == span.start && merged[n-1].preset == span.preset {
+			merged[n-1].end = span.end
+			continue
+		}
+
+		merged = append(merged, span)
+	}
+
+	// the last entry of the week can continue on Sunday
+	if n := len(merged); n > 1 && merged[0].start == 0 && merged[n-1].end == minutesPerWeek && merged[0].preset == merged[n-1].preset {
+		merged[n-1].end = minutesPerWeek + merged[0].end
+		merged = merged[1:]
+	}
+
+	entries := make([]programEntry, len(merged))
+	for i, span := range merged {
+		start, end := span.start%minutesPerWeek, span.end%minutesPerWeek
+		entries[i] = programEntry{
+			StartDayOfWeek: start / minutesPerDay,
+			StartHour:      start % minutesPerDay / 60,
+			StartMin:       start % 60,
+			EndDayOfWeek:   end / minutesPerDay,
+			EndHour:        end % minutesPerDay / 60,
+			EndMin:         end % 60,
+			TargetState:    int(span.preset),
+		}
+	}
+
+	return thermostatPrograms{Programs: &entries}
+}
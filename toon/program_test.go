@@ -0,0 +1,217 @@
+package toon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fullDay returns blocks covering a day with the given boundaries, e.g. 06:30 and 22:00
+func fullDay(day time.Weekday, boundaries ...ClockTime) []ProgramBlock {
+	blocks := []ProgramBlock{}
+	start := ClockTime(0)
+	for i, end := range append(boundaries, EndOfDay) {
+		blocks = append(blocks, ProgramBlock{Day: day, Start: start, End: end, Preset: Preset(i % 4)})
+		start = end
+	}
+
+	return blocks
+}
+
+func fullWeek(modify func(blocks []ProgramBlock) []ProgramBlock) WeekProgram {
+	program := WeekProgram{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		program.Blocks = append(program.Blocks, fullDay(d, NewClockTime(6, 30), NewClockTime(22, 0))...)
+	}
+
+	if modify != nil {
+		program.Blocks = modify(program.Blocks)
+	}
+
+	return program
+}
+
+func TestWeekProgramValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		program WeekProgram
+		want    []error
+	}{
+		{"valid", fullWeek(nil), nil},
+		{"empty", WeekProgram{}, []error{ErrProgramGap}},
+		{"gap", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			b[1].Start = NewClockTime(7, 0)
+			return b
+		}), []error{ErrProgramGap}},
+		{"overlap", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			b[1].Start = NewClockTime(6, 0)
+			return b
+		}), []error{ErrProgramOverlap}},
+		{"end before start", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			return append(b, ProgramBlock{Day: time.Monday, Start: NewClockTime(10, 0), End: NewClockTime(9, 0)})
+		}), []error{ErrProgramInvalidBlock, ErrProgramOverlap}},
+		{"holiday preset", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			b[0].Preset = PresetHoliday
+			return b
+		}), []error{ErrProgramInvalidBlock}},
+		{"unknown day", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			return append(b, ProgramBlock{Day: 7, Start: 0, End: EndOfDay})
+		}), []error{ErrProgramInvalidBlock}},
+		{"too many blocks", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			blocks := []ProgramBlock{}
+			for _, block := range b {
+				if block.Day != time.Tuesday {
+					blocks = append(blocks, block)
+				}
+			}
+
+			return append(blocks, fullDay(time.Tuesday, 60, 120, 180, 240, 300, 360)...)
+		}), []error{ErrProgramTooManyBlocks}},
+		{"six entries and the entry of the previous night", fullWeek(func(b []ProgramBlock) []ProgramBlock {
+			blocks := []ProgramBlock{}
+			for _, block := range b {
+				if block.Day != time.Tuesday {
+					blocks = append(blocks, block)
+				}
+			}
+
+			// the first block continues the last block of Monday
+			tuesday := fullDay(time.Tuesday, 60, 120, 180, 240, 300, 360)
+			tuesday[0].Preset = Preset(2)
+			return append(blocks, tuesday...)
+		}), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.program.Validate()
+			if len(test.want) == 0 && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+
+			for _, want := range test.want {
+				if !errors.Is(err, want) {
+					t.Errorf("Validate() = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClockTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ClockTime
+		err   bool
+	}{
+		{"00:00", 0, false},
+		{"06:30", NewClockTime(6, 30), false},
+		{"24:00", EndOfDay, false},
+		{"24:01", 0, true},
+		{"6:30", 0, true},
+		{"06:60", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseClockTime(test.value)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("ParseClockTime(%q) = %v, %v", test.value, got, err)
+		}
+	}
+}
+
+func loadThermostatPrograms(t *testing.T) thermostatPrograms {
+	t.Helper()
+	data, err := os.ReadFile("testdata/thermostat_programs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	programs := thermostatPrograms{}
+	if err := json.Unmarshal(data, &programs); err != nil {
+		t.Fatal(err)
+	}
+
+	return programs
+}
+
+func TestThermostatProgramsFixture(t *testing.T) {
+	program, err := loadThermostatPrograms(t).weekProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	tests := []struct {
+		day  time.Weekday
+		at   ClockTime
+		want Preset
+	}{
+		// the sleep entry of Sunday 23:00 until Monday 06:30 is split at midnight
+		{time.Sunday, NewClockTime(23, 30), PresetSleep},
+		{time.Monday, NewClockTime(3, 0), PresetSleep},
+		{time.Monday, NewClockTime(6, 30), PresetComfort},
+		{time.Wednesday, NewClockTime(12, 0), PresetAway},
+		{time.Saturday, NewClockTime(18, 0), PresetHome},
+		// the entry of Saturday 23:00 wraps around to Sunday
+		{time.Sunday, NewClockTime(0, 0), PresetSleep},
+	}
+
+	for _, test := range tests {
+		if got, ok := program.At(test.day, test.at); !ok || got != test.want {
+			t.Errorf("At(%v, %v) = %v, %v, want %v", test.day, test.at, got, ok, test.want)
+		}
+	}
+
+	roundTrip, err := newThermostatPrograms(*program).weekProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !roundTrip.Equal(*program) {
+		t.Fatalf("round trip changed the program")
+	}
+}
+
+func TestThermostatProgramsPayloadRoundTrip(t *testing.T) {
+	payload := loadThermostatPrograms(t)
+	program, err := payload.weekProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writing back an unchanged program sends the same entries, the split overnight blocks are merged again
+	got, want := *newThermostatPrograms(*program).Programs, *payload.Programs
+	sortEntries := func(entries []programEntry) {
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			return a.StartDayOfWeek*minutesPerDay+a.StartHour*60+a.StartMin < b.StartDayOfWeek*minutesPerDay+b.StartHour*60+b.StartMin
+		})
+	}
+	sortEntries(got)
+	sortEntries(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %d entries %+v, want %d entries %+v", len(got), got, len(want), want)
+	}
+}
+
+func TestThermostatProgramsUnexpected(t *testing.T) {
+	for _, payload := range []string{`{}`, `{"blocks":[]}`, `{"programs":[{"startDayOfWeek":8}]}`, `{"programs":[{"startHour":25}]}`} {
+		programs := thermostatPrograms{}
+		if err := json.Unmarshal([]byte(payload), &programs); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := programs.weekProgram(); !errors.Is(err, errUnexpectedPrograms) {
+			t.Errorf("weekProgram(%v) = %v, want %v", payload, err, errUnexpectedPrograms)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedByProvider is returned when an endpoint is not available for the provider
+// of the authenticator, check with errors.Is(err, toon.ErrUnsupportedByProvider)
+var ErrUnsupportedByProvider = errors.New("endpoint not supported by provider")
+
+// Provider describes the capabilities of a Toon provider (tenant)
+type Provider struct {
+	// TenantID as supplied to auth.NewToonAuthenticator, e.g. eneco or viesgo
+	TenantID string
+	Name     string
+	// Unsupported contains the endpoints which are not available for the provider, endpoints are
+	// the Toon API paths without agreement id, e.g. /consumption/districtheat/data
+	Unsupported []string
+	// Variants maps an endpoint to a provider specific variant which is used instead
+	Variants map[string]string
+}
+
+// Supports returns true when the endpoint is available for the provider
+func (p Provider) Supports(endpoint string) bool {
+	for _, unsupported := range p.Unsupported {
+		if unsupported == endpoint {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Endpoint returns the endpoint to use for the provider, which is either the provider
+// specific variant or the endpoint itself
+func (p Provider) Endpoint(endpoint string) (string, error) {
+	if !p.Supports(endpoint) {
+		return "", fmt.Errorf("%w: %s does not support %s", ErrUnsupportedByProvider, p.TenantID, endpoint)
+	}
+
+	if variant, ok := p.Variants[endpoint]; ok {
+		return variant, nil
+	}
+
+	return endpoint, nil
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{
+		"eneco": {
+			TenantID: "eneco",
+			Name:     "Eneco",
+			Variants: map[string]string{
+				electricityGraphDataEndpoint: enecoGraphDataEndpoint,
+			},
+		},
+		// The Toon API does not publish which endpoints a tenant supports, this list is derived from the
+		// endpoints themselves: the eneco variant is specific to Eneco and district heating is only delivered
+		// through Eneco. It is not verified against a Viesgo account, use RegisterProvider to override it.
+		"viesgo": {
+			TenantID: "viesgo",
+			Name:     "Viesgo",
+			Unsupported: []string{
+				districtHeatGraphDataEndpoint,
+				enecoGraphDataEndpoint,
+			},
+		},
+	}
+)
+
+// RegisterProvider adds or replaces the capabilities of a provider
+func RegisterProvider(provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[strings.ToLower(provider.TenantID)] = provider
+}
+
+// LookupProvider returns the capabilities of a provider by tenant id
+func LookupProvider(tenantID string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[strings.ToLower(tenantID)]
+	return provider, ok
+}
+
+// Providers returns all registered providers ordered by tenant id
+func Providers() []Provider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	list := make([]Provider, 0, len(providers))
+	for _, provider := range providers {
+		list = append(list, provider)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].TenantID < list[j].TenantID })
+	return list
+}
+
+// resolveEndpoint returns the endpoint to use for a tenant, unknown tenants support all endpoints
+func resolveEndpoint(tenantID, endpoint string) (string, error) {
+	provider, ok := LookupProvider(tenantID)
+	if !ok {
+		return endpoint, nil
+	}
+
+	return provider.Endpoint(endpoint)
+}
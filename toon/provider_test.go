@@ -0,0 +1,105 @@
+package toon
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// registerTestProvider registers a provider for the duration of the test
+func registerTestProvider(t *testing.T, provider Provider) {
+	RegisterProvider(provider)
+	t.Cleanup(func() {
+		providersMu.Lock()
+		defer providersMu.Unlock()
+		delete(providers, strings.ToLower(provider.TenantID))
+	})
+}
+
+func TestUnsupportedEndpointIsLoggedAsTemplate(t *testing.T) {
+	registerTestProvider(t, Provider{TenantID: "test-unsupported", Name: "Test", Unsupported: []string{statusEndpoint}})
+
+	var buf bytes.Buffer
+	telemetry.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer telemetry.SetLogger(nil)
+
+	authenticator := auth.NewToonAuthenticator("", "", "test-unsupported", "", "", "", 0)
+	err := get(statusEndpoint, nil, "1", authenticator, nil, false)
+	if err == nil || !errors.Is(err.Err, ErrUnsupportedByProvider) {
+		t.Fatalf("get() = %v, want %v", err, ErrUnsupportedByProvider)
+	}
+
+	if !strings.Contains(buf.String(), "endpoint=/{agreementId}/status") {
+		t.Fatalf("log %q does not contain the endpoint template", buf.String())
+	}
+}
+
+func TestProviderRouting(t *testing.T) {
+	paths := []string{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	})
+
+	tests := []struct {
+		tenantID string
+		call     func(authenticator *auth.ToonAuthenticator) *ErrorResponse
+		path     string
+		err      error
+	}{
+		{
+			tenantID: "eneco",
+			call: func(authenticator *auth.ToonAuthenticator) *ErrorResponse {
+				_, err := GetElectricityGraphData(authenticator, "1", 0, 0, IntervalDays)
+				return err
+			},
+			path: "/toon/v3/1" + enecoGraphDataEndpoint,
+		},
+		{
+			tenantID: "viesgo",
+			call: func(authenticator *auth.ToonAuthenticator) *ErrorResponse {
+				_, err := GetElectricityGraphData(authenticator, "1", 0, 0, IntervalDays)
+				return err
+			},
+			path: "/toon/v3/1" + electricityGraphDataEndpoint,
+		},
+		{
+			tenantID: "viesgo",
+			call: func(authenticator *auth.ToonAuthenticator) *ErrorResponse {
+				_, err := GetDistrictHeatGraphData(authenticator, "1", 0, 0, IntervalDays)
+				return err
+			},
+			err: ErrUnsupportedByProvider,
+		},
+		{
+			tenantID: "unknown",
+			call: func(authenticator *auth.ToonAuthenticator) *ErrorResponse {
+				_, err := GetDistrictHeatGraphData(authenticator, "1", 0, 0, IntervalDays)
+				return err
+			},
+			path: "/toon/v3/1" + districtHeatGraphDataEndpoint,
+		},
+	}
+
+	for _, test := range tests {
+		paths = nil
+		err := test.call(auth.NewToonAuthenticator("id", "secret", test.tenantID, "", "", "", 0))
+		if test.err != nil {
+			if err == nil || !errors.Is(err.Err, test.err) || len(paths) != 0 {
+				t.Fatalf("%v: got %v after requests %v, want %v without requests", test.tenantID, err, paths, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil || len(paths) != 1 || paths[0] != test.path {
+			t.Fatalf("%v: got %v and requests %v, want a request to %v", test.tenantID, err, paths, test.path)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package toon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned when a request is not send because the client side
+// rate limit is reached, check with errors.Is(err, toon.ErrRateLimited)
+var ErrRateLimited = errors.New("rate limit reached")
+
+var rateLimiter atomic.Pointer[RateLimiter]
+
+// SetRateLimiter sets the rate limiter used for all requests to the Toon API,
+// supply nil to disable rate limiting
+func SetRateLimiter(rl *RateLimiter) {
+	rateLimiter.Store(rl)
+}
+
+// CallKind is the kind of call counted against a quota
+type CallKind int
+
+// Call kinds, a GET request is a read all other requests are writes
+const (
+	CallRead CallKind = iota
+	CallWrite
+)
+
+// RateLimitMode defines what happens when the rate limit is reached
+type RateLimitMode int
+
+// Rate limit modes
+const (
+	// RateLimitBlock waits until there is budget again, at most MaxWait, waiting stops when the
+	// context of a PollStatus call is done or the rate limiter is closed
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitFailFast returns ErrRateLimited immediately
+	RateLimitFailFast
+)
+
+// Quota is the number of calls allowed per minute and per day, 0 means unlimited
+type Quota struct {
+	PerMinute int
+	PerDay    int
+}
+
+// Budget is the remaining number of calls, -1 means unlimited
+type Budget struct {
+	Minute int
+	Day    int
+}
+
+// RateLimitConfig contains the settings of a RateLimiter
+type RateLimitConfig struct {
+	Read  Quota
+	Write Quota
+	Mode  RateLimitMode
+	// MaxWait is the maximum time to block in RateLimitBlock mode, 0 means no maximum
+	MaxWait time.Duration
+	// BackgroundReserve is the budget reserved for interactive requests, background requests such as
+	// PollStatus and Watch can not use it. Other background pollers can check Yield before polling.
+	BackgroundReserve int
+}
+
+// RateLimiter is a rate limiter with separate limits for reads and writes, each kind has a per minute
+// token bucket which is refilled continuously and a per day sliding window, so no period of 24 hours
+// contains more calls than the daily quota
+type RateLimiter struct {
+	config RateLimitConfig
+	mu     sync.Mutex
+	limits map[CallKind]*quotaLimits
+	done   chan struct{}
+	once   sync.Once
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// quotaLimits are the limits of a kind of call, nil when the quota is unlimited
+type quotaLimits struct {
+	minute *tokenBucket
+	day    *windowCounter
+}
+
+// windowCounter counts the calls of the last period
+type windowCounter struct {
+	limit  int
+	period time.Duration
+	// calls are the times of the calls in the period, oldest first
+	calls []time.Time
+}
+
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	// rate is the number of tokens added per second
+	rate float64
+	last time.Time
+}
+
+// NewRateLimiter creates a new rate limiter, use SetRateLimiter to enable it
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{
+		config: config,
+		limits: map[CallKind]*quotaLimits{},
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+	rl.sleep = rl.sleepContext
+
+	now := rl.now()
+	for kind, quota := range map[CallKind]Quota{CallRead: config.Read, CallWrite: config.Write} {
+		rl.limits[kind] = &quotaLimits{minute: newTokenBucket(quota.PerMinute, time.Minute, now), day: newWindowCounter(quota.PerDay, 24*time.Hour)}
+	}
+
+	return rl
+}
+
+func newWindowCounter(limit int, period time.Duration) *windowCounter {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &windowCounter{limit: limit, period: period}
+}
+
+func newTokenBucket(limit int, period time.Duration, now time.Time) *tokenBucket {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &tokenBucket{
+		capacity: float64(limit),
+		tokens:   float64(limit),
+		rate:     float64(limit) / period.Seconds(),
+		last:     now,
+	}
+}
+
+// Remaining returns the remaining budget for a kind of call
+func (rl *RateLimiter) Remaining(kind CallKind) Budget {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	l := rl.limits[kind]
+	return Budget{Minute: l.minute.remaining(now), Day: l.day.remaining(now)}
+}
+
+// Yield returns true when the remaining budget is within the BackgroundReserve, background pollers
+// which do not use PollStatus should skip their poll so interactive requests can use the budget
+func (rl *RateLimiter) Yield(kind CallKind) bool {
+	budget := rl.Remaining(kind)
+	reserve := rl.config.BackgroundReserve
+	return (budget.Minute >= 0 && budget.Minute <= reserve) || (budget.Day >= 0 && budget.Day <= reserve)
+}
+
+// Close stops all requests which are waiting for budget, they fail with ErrRateLimited
+func (rl *RateLimiter) Close() {
+	rl.once.Do(func() { close(rl.done) })
+}
+
+// wait takes a token for the kind of call, blocking or failing depending on the mode, background
+// calls can not use the BackgroundReserve
+func (rl *RateLimiter) wait(ctx context.Context, kind CallKind, background bool) error {
+	reserve := 0
+	if background {
+		reserve = rl.config.BackgroundReserve
+	}
+
+	var waited time.Duration
+	for {
+		delay := rl.take(kind, reserve)
+		if delay == 0 {
+			return nil
+		}
+
+		if delay < 0 || rl.config.Mode == RateLimitFailFast || (rl.config.MaxWait > 0 && waited+delay > rl.config.MaxWait) {
+			return ErrRateLimited
+		}
+
+		if err := rl.sleep(ctx, delay); err != nil {
+			return fmt.Errorf("%w: %w", ErrRateLimited, err)
+		}
+
+		waited += delay
+	}
+}
+
+func (rl *RateLimiter) sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rl.done:
+		return errors.New("rate limiter closed")
+	}
+}
+
+// take counts a call against the limits of a kind, leaving the reserve unused. When there is no budget
+// nothing is counted and the time until there is budget again is returned, -1 when there never is.
+func (rl *RateLimiter) take(kind CallKind, reserve int) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	l := rl.limits[kind]
+	minute, day := l.minute.delay(now, reserve), l.day.delay(now, reserve)
+	if minute < 0 || day < 0 {
+		return -1
+	}
+
+	if delay := max(minute, day); delay > 0 {
+		return delay
+	}
+
+	if l.minute != nil {
+		l.minute.tokens--
+	}
+
+	if l.day != nil {
+		l.day.calls = append(l.day.calls, now)
+	}
+
+	return 0
+}
+
+// prune removes the calls which are no longer in the period
+func (w *windowCounter) prune(now time.Time) {
+	i := 0
+	for i < len(w.calls) && !w.calls[i].After(now.Add(-w.period)) {
+		i++
+	}
+
+	w.calls = w.calls[i:]
+}
+
+func (w *windowCounter) remaining(now time.Time) int {
+	if w == nil {
+		return -1
+	}
+
+	w.prune(now)
+	return w.limit - len(w.calls)
+}
+
+func (w *windowCounter) delay(now time.Time, reserve int) time.Duration {
+	if w == nil {
+		return 0
+	}
+
+	if reserve >= w.limit {
+		return -1
+	}
+
+	w.prune(now)
+	if len(w.calls)+reserve < w.limit {
+		return 0
+	}
+
+	// the oldest calls leave the period first
+	return w.calls[len(w.calls)+reserve-w.limit].Add(w.period).Sub(now)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+func (b *tokenBucket) remaining(now time.Time) int {
+	if b == nil {
+		return -1
+	}
+
+	b.refill(now)
+	return int(b.tokens)
+}
+
+func (b *tokenBucket) delay(now time.Time, reserve int) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	needed := float64(1 + reserve)
+	if needed > b.capacity {
+		return -1
+	}
+
+	b.refill(now)
+	if b.tokens >= needed {
+		return 0
+	}
+
+	return time.Duration(math.Ceil((needed - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// callKind returns the kind of call for a HTTP method
+func callKind(method string) CallKind {
+	if method == http.MethodGet {
+		return CallRead
+	}
+
+	return CallWrite
+}
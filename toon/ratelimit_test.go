@@ -0,0 +1,241 @@
+package toon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// newTestRateLimiter returns a rate limiter with a fake clock which is advanced by sleep
+func newTestRateLimiter(config RateLimitConfig) (*RateLimiter, *time.Duration) {
+	rl := NewRateLimiter(config)
+	now := time.Now()
+	slept := new(time.Duration)
+	rl.now = func() time.Time { return now }
+	rl.sleep = func(ctx context.Context, d time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		*slept += d
+		now = now.Add(d)
+		return nil
+	}
+
+	return rl, slept
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 2}, Mode: RateLimitFailFast})
+	for i := 0; i < 2; i++ {
+		if err := rl.wait(context.Background(), CallRead, false); err != nil {
+			t.Fatalf("call %d: wait() = %v", i, err)
+		}
+	}
+
+	if err := rl.wait(context.Background(), CallRead, false); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("wait() = %v, want %v", err, ErrRateLimited)
+	}
+
+	if err := rl.wait(context.Background(), CallWrite, false); err != nil {
+		t.Fatalf("writes are unlimited, wait() = %v", err)
+	}
+}
+
+func TestRateLimiterBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		err     error
+		slept   time.Duration
+	}{
+		// one token per 30 seconds is refilled with 2 calls per minute
+		{"waits for refill", 0, nil, 30 * time.Second},
+		{"within max wait", time.Minute, nil, 30 * time.Second},
+		{"exceeds max wait", 10 * time.Second, ErrRateLimited, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rl, slept := newTestRateLimiter(RateLimitConfig{Write: Quota{PerMinute: 2}, MaxWait: test.maxWait})
+			rl.wait(context.Background(), CallWrite, false)
+			rl.wait(context.Background(), CallWrite, false)
+			if err := rl.wait(context.Background(), CallWrite, false); !errors.Is(err, test.err) {
+				t.Fatalf("wait() = %v, want %v", err, test.err)
+			}
+
+			if *slept != test.slept {
+				t.Fatalf("slept %v, want %v", *slept, test.slept)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRemaining(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 10, PerDay: 100}, BackgroundReserve: 5})
+	if got := rl.Remaining(CallWrite); got != (Budget{Minute: -1, Day: -1}) {
+		t.Fatalf("Remaining(write) = %+v, want unlimited", got)
+	}
+
+	for i := 0; i < 4; i++ {
+		rl.wait(context.Background(), CallRead, false)
+	}
+
+	if got := rl.Remaining(CallRead); got != (Budget{Minute: 6, Day: 96}) {
+		t.Fatalf("Remaining(read) = %+v", got)
+	}
+
+	if rl.Yield(CallRead) {
+		t.Fatal("Yield() = true with budget above the reserve")
+	}
+
+	rl.wait(context.Background(), CallRead, false)
+	if !rl.Yield(CallRead) {
+		t.Fatal("Yield() = false with budget within the reserve")
+	}
+
+	// the minute bucket refills after a minute, the day bucket does not
+	rl.sleep(context.Background(), time.Minute)
+	if got := rl.Remaining(CallRead); got.Minute != 10 || got.Day != 95 {
+		t.Fatalf("Remaining(read) after a minute = %+v", got)
+	}
+}
+
+func TestRateLimiterDayWindow(t *testing.T) {
+	rl, slept := newTestRateLimiter(RateLimitConfig{Read: Quota{PerDay: 3}})
+	for i := 0; i < 3; i++ {
+		if err := rl.wait(context.Background(), CallRead, false); err != nil {
+			t.Fatalf("call %d: wait() = %v", i, err)
+		}
+
+		rl.sleep(context.Background(), time.Hour)
+	}
+
+	// the daily quota is not refilled continuously, no budget comes back before the first call is a day old
+	rl.sleep(context.Background(), 20*time.Hour)
+	if got := rl.Remaining(CallRead); got.Day != 0 {
+		t.Fatalf("Remaining(read) after 23 hours = %+v, want no budget", got)
+	}
+
+	*slept = 0
+	if err := rl.wait(context.Background(), CallRead, false); err != nil {
+		t.Fatalf("wait() = %v", err)
+	}
+
+	if *slept != time.Hour {
+		t.Fatalf("slept %v, want %v", *slept, time.Hour)
+	}
+
+	// the window slides, the second call of the previous day leaves it an hour later
+	if got := rl.Remaining(CallRead); got.Day != 0 {
+		t.Fatalf("Remaining(read) = %+v, want no budget", got)
+	}
+
+	rl.sleep(context.Background(), time.Hour)
+	if got := rl.Remaining(CallRead); got.Day != 1 {
+		t.Fatalf("Remaining(read) an hour later = %+v, want 1", got)
+	}
+}
+
+func TestRateLimiterBackgroundReserve(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 10, PerDay: 5}, Mode: RateLimitFailFast, BackgroundReserve: 2})
+	for i := 0; i < 3; i++ {
+		if err := rl.wait(context.Background(), CallRead, true); err != nil {
+			t.Fatalf("background call %d: wait() = %v", i, err)
+		}
+	}
+
+	// the last 2 calls of the day are reserved for interactive requests
+	if err := rl.wait(context.Background(), CallRead, true); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("background wait() = %v, want %v", err, ErrRateLimited)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := rl.wait(context.Background(), CallRead, false); err != nil {
+			t.Fatalf("interactive call %d: wait() = %v", i, err)
+		}
+	}
+
+	// a reserve larger than the quota never leaves budget for background requests
+	rl, _ = newTestRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 2}, BackgroundReserve: 2})
+	if err := rl.wait(context.Background(), CallRead, true); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("background wait() = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	rl, slept := newTestRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 1}})
+	rl.wait(context.Background(), CallRead, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rl.wait(ctx, CallRead, false); !errors.Is(err, ErrRateLimited) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait() = %v, want %v and %v", err, ErrRateLimited, context.Canceled)
+	}
+
+	if *slept != 0 {
+		t.Fatalf("slept %v after cancel", *slept)
+	}
+
+	// the real sleep stops when the rate limiter is closed
+	rl = NewRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 1}})
+	rl.wait(context.Background(), CallRead, false)
+	rl.Close()
+	done := make(chan error)
+	go func() { done <- rl.wait(context.Background(), CallRead, false) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("wait() = %v, want %v", err, ErrRateLimited)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait() did not stop after Close")
+	}
+}
+
+func TestCallKind(t *testing.T) {
+	for method, want := range map[string]CallKind{http.MethodGet: CallRead, http.MethodPut: CallWrite, http.MethodPost: CallWrite, http.MethodDelete: CallWrite} {
+		if got := callKind(method); got != want {
+			t.Errorf("callKind(%v) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+type rejectTracer struct {
+	telemetry.NopTracer
+	started  int
+	rejected []telemetry.RejectReason
+}
+
+func (t *rejectTracer) RequestStart(telemetry.RequestInfo) { t.started++ }
+
+func (t *rejectTracer) RequestRejected(info telemetry.RequestInfo, reason telemetry.RejectReason, err error) {
+	t.rejected = append(t.rejected, reason)
+}
+
+func TestRateLimitedRequestIsRejected(t *testing.T) {
+	rl, _ := newTestRateLimiter(RateLimitConfig{Read: Quota{PerMinute: 1}, Mode: RateLimitFailFast})
+	if err := rl.wait(context.Background(), CallRead, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := &rejectTracer{}
+	SetRateLimiter(rl)
+	telemetry.SetTracer(tracer)
+	defer SetRateLimiter(nil)
+	defer telemetry.SetTracer(nil)
+
+	err := get(statusEndpoint, nil, "1", &auth.ToonAuthenticator{}, nil, false)
+	if err == nil || !errors.Is(err.Err, ErrRateLimited) {
+		t.Fatalf("get() = %v, want %v", err, ErrRateLimited)
+	}
+
+	if tracer.started != 0 || len(tracer.rejected) != 1 || tracer.rejected[0] != telemetry.RejectRateLimit {
+		t.Fatalf("started %d, rejected %v, want only a %v rejection", tracer.started, tracer.rejected, telemetry.RejectRateLimit)
+	}
+}
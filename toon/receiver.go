@@ -0,0 +1,340 @@
+package toon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// ErrWebhookQueueFull is passed to WebhookHandler.OnError when a pushed event is dropped because
+// the callbacks can not keep up
+var ErrWebhookQueueFull = errors.New("webhook queue full")
+
+// Webhook receiver defaults
+const (
+	// WebhookSecretParam is the query parameter of the callback URL which holds the shared secret
+	WebhookSecretParam = "secret"
+	// WebhookSecretHeader can be used instead of WebhookSecretParam by proxies in front of the receiver
+	WebhookSecretHeader = "X-Toon-Secret"
+	// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 of the request body, optionally prefixed with sha256=
+	WebhookSignatureHeader = "X-Toon-Signature"
+	// DefaultWebhookMaxBodySize is the maximum size of a pushed request body in bytes
+	DefaultWebhookMaxBodySize = 1 << 20
+	// DefaultWebhookQueueSize is the number of events which can wait for their callbacks
+	DefaultWebhookQueueSize = 64
+)
+
+// WebhookEvent is a status fragment pushed by Toon to a webhook, only the parts of the
+// status which changed are set
+type WebhookEvent struct {
+	// CommonName is the common name of the Toon display which sent the update
+	CommonName       string
+	ReceivedAt       time.Time
+	ThermostatInfo   *ThermostatInfo
+	ThermostatStates *ThermostatStates
+	PowerUsage       *PowerUsage
+	GasUsage         *GasUsage
+	DeviceStatusInfo *DeviceStatusInfo
+	DeviceConfigInfo *DeviceConfigInfo
+	SmokeDetectors   *SmokeDetectors
+	// Raw is the pushed updateDataSet as received
+	Raw json.RawMessage
+}
+
+type webhookPayload struct {
+	CommonName    string          `json:"commonName"`
+	UpdateDataSet json.RawMessage `json:"updateDataSet"`
+}
+
+type webhookDataSet struct {
+	ThermostatInfo   *ThermostatInfo   `json:"thermostatInfo"`
+	ThermostatStates *ThermostatStates `json:"thermostatStates"`
+	PowerUsage       *PowerUsage       `json:"powerUsage"`
+	GasUsage         *GasUsage         `json:"gasUsage"`
+	DeviceStatusInfo *DeviceStatusInfo `json:"deviceStatusInfo"`
+	DeviceConfigInfo *DeviceConfigInfo `json:"deviceConfigInfo"`
+	SmokeDetectors   *SmokeDetectors   `json:"smokeDetectors"`
+}
+
+// WebhookHandler is a http.Handler which receives the events pushed to a webhook. A request is validated
+// and decoded before it is answered, the callbacks are called afterwards in order from a single goroutine
+// so slow callbacks do not make Toon mark the webhook as failing. Set the callbacks before serving requests.
+// The zero value accepts requests without secret using the defaults, use NewWebhookHandler to require one.
+type WebhookHandler struct {
+	secret string
+	queue  chan WebhookEvent
+	done   chan struct{}
+	start  sync.Once
+	// mu guards closed so no event is queued after Close returned
+	mu     sync.RWMutex
+	closed bool
+	// SignatureKey enables validation of the WebhookSignatureHeader when set
+	SignatureKey []byte
+	// CommonNames restricts the accepted events to these displays when not empty
+	CommonNames []string
+	// MaxBodySize is the maximum size of a request body in bytes, default DefaultWebhookMaxBodySize
+	MaxBodySize int64
+
+	// OnEvent is called for every event, before the typed callbacks
+	OnEvent            func(event WebhookEvent)
+	OnThermostatInfo   func(commonName string, info ThermostatInfo)
+	OnThermostatStates func(commonName string, states ThermostatStates)
+	OnPowerUsage       func(commonName string, usage PowerUsage)
+	OnGasUsage         func(commonName string, usage GasUsage)
+	OnDeviceStatusInfo func(commonName string, info DeviceStatusInfo)
+	OnDeviceConfigInfo func(commonName string, info DeviceConfigInfo)
+	OnSmokeDetectors   func(commonName string, detectors SmokeDetectors)
+	// OnError is called when an event is dropped or a callback panics, it can be called
+	// from the request goroutine and the callback goroutine at the same time
+	OnError func(err error)
+}
+
+// NewWebhookHandler creates a WebhookHandler which only accepts requests carrying the shared secret,
+// add the secret to the callback URL as WebhookSecretParam query parameter when subscribing. An empty
+// secret disables the check. Call Close to stop the handler.
+func NewWebhookHandler(secret string) *WebhookHandler {
+	h := &WebhookHandler{secret: secret, MaxBodySize: DefaultWebhookMaxBodySize}
+	h.init()
+	return h
+}
+
+// init creates the queue and starts the callback goroutine once, so the zero value can be used
+func (h *WebhookHandler) init() {
+	h.start.Do(func() {
+		h.queue = make(chan WebhookEvent, DefaultWebhookQueueSize)
+		h.done = make(chan struct{})
+		go h.run()
+	})
+}
+
+// ServeHTTP implements http.Handler
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.init()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.validSecret(r) {
+		telemetry.Logger().Warn("toon webhook rejected", "remote_addr", r.RemoteAddr, "reason", "invalid secret")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	maxBodySize := h.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultWebhookMaxBodySize
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		telemetry.Logger().Warn("toon webhook rejected", "remote_addr", r.RemoteAddr, "reason", "unable to read body", "error", err)
+		http.Error(w, "unable to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.validSignature(r, body) {
+		telemetry.Logger().Warn("toon webhook rejected", "remote_addr", r.RemoteAddr, "reason", "invalid signature")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := decodeWebhookEvent(body)
+	if err != nil {
+		telemetry.Logger().Warn("toon webhook rejected", "remote_addr", r.RemoteAddr, "reason", "invalid payload", "error", err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if !h.acceptsCommonName(event.CommonName) {
+		telemetry.Logger().Warn("toon webhook rejected", "remote_addr", r.RemoteAddr, "reason", "unknown display", "common_name", event.CommonName)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	h.mu.RLock()
+	if h.closed {
+		h.mu.RUnlock()
+		http.Error(w, "webhook handler closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	dropped := false
+	select {
+	case h.queue <- event:
+	default:
+		dropped = true
+	}
+	h.mu.RUnlock()
+
+	if dropped {
+		// answer anyway, Toon would otherwise retry or disable the webhook while we are busy
+		telemetry.Logger().Warn("toon webhook event dropped", "common_name", event.CommonName)
+		h.fail(fmt.Errorf("%w: event of %v dropped", ErrWebhookQueueFull, event.CommonName))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Close stops dispatching events, events which are still queued are dropped and
+// requests received afterwards are answered with 503 Service Unavailable
+func (h *WebhookHandler) Close() {
+	h.init()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+}
+
+func (h *WebhookHandler) validSecret(r *http.Request) bool {
+	if len(h.secret) == 0 {
+		return true
+	}
+
+	secret := r.Header.Get(WebhookSecretHeader)
+	if len(secret) == 0 {
+		secret = r.URL.Query().Get(WebhookSecretParam)
+	}
+
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(h.secret)) == 1
+}
+
+func (h *WebhookHandler) validSignature(r *http.Request, body []byte) bool {
+	if len(h.SignatureKey) == 0 {
+		return true
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(WebhookSignatureHeader), "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, h.SignatureKey)
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+func (h *WebhookHandler) acceptsCommonName(commonName string) bool {
+	if len(h.CommonNames) == 0 {
+		return true
+	}
+
+	for _, name := range h.CommonNames {
+		if name == commonName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeWebhookEvent decodes a pushed request body, the fragments are decoded into the status models
+func decodeWebhookEvent(body []byte) (WebhookEvent, error) {
+	payload := webhookPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return WebhookEvent{}, err
+	}
+
+	if len(payload.UpdateDataSet) == 0 {
+		return WebhookEvent{}, errors.New("missing updateDataSet")
+	}
+
+	data := webhookDataSet{}
+	if err := json.Unmarshal(payload.UpdateDataSet, &data); err != nil {
+		return WebhookEvent{}, err
+	}
+
+	return WebhookEvent{
+		CommonName:       payload.CommonName,
+		ReceivedAt:       time.Now(),
+		ThermostatInfo:   data.ThermostatInfo,
+		ThermostatStates: data.ThermostatStates,
+		PowerUsage:       data.PowerUsage,
+		GasUsage:         data.GasUsage,
+		DeviceStatusInfo: data.DeviceStatusInfo,
+		DeviceConfigInfo: data.DeviceConfigInfo,
+		SmokeDetectors:   data.SmokeDetectors,
+		Raw:              payload.UpdateDataSet,
+	}, nil
+}
+
+func (h *WebhookHandler) run() {
+	for {
+		select {
+		case <-h.done:
+			return
+		case event := <-h.queue:
+			h.dispatch(event)
+		}
+	}
+}
+
+// dispatch calls the callbacks for an event, a panicking callback is reported to OnError
+func (h *WebhookHandler) dispatch(event WebhookEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			telemetry.Logger().Error("toon webhook callback panicked", "common_name", event.CommonName, "panic", r)
+			h.fail(fmt.Errorf("webhook callback panicked: %v", r))
+		}
+	}()
+
+	if h.OnEvent != nil {
+		h.OnEvent(event)
+	}
+
+	name := event.CommonName
+	if event.ThermostatInfo != nil && h.OnThermostatInfo != nil {
+		h.OnThermostatInfo(name, *event.ThermostatInfo)
+	}
+
+	if event.ThermostatStates != nil && h.OnThermostatStates != nil {
+		h.OnThermostatStates(name, *event.ThermostatStates)
+	}
+
+	if event.PowerUsage != nil && h.OnPowerUsage != nil {
+		h.OnPowerUsage(name, *event.PowerUsage)
+	}
+
+	if event.GasUsage != nil && h.OnGasUsage != nil {
+		h.OnGasUsage(name, *event.GasUsage)
+	}
+
+	if event.DeviceStatusInfo != nil && h.OnDeviceStatusInfo != nil {
+		h.OnDeviceStatusInfo(name, *event.DeviceStatusInfo)
+	}
+
+	if event.DeviceConfigInfo != nil && h.OnDeviceConfigInfo != nil {
+		h.OnDeviceConfigInfo(name, *event.DeviceConfigInfo)
+	}
+
+	if event.SmokeDetectors != nil && h.OnSmokeDetectors != nil {
+		h.OnSmokeDetectors(name, *event.SmokeDetectors)
+	}
+}
+
+func (h *WebhookHandler) fail(err error) {
+	if h.OnError != nil {
+		h.OnError(err)
+	}
+}
@@ -0,0 +1,179 @@
+package toon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const webhookBody = `{"commonName":"eneco-001-123456","updateDataSet":{"thermostatInfo":{"currentSetpoint":2050},"powerUsage":{"value":420}}}`
+
+func signWebhook(key []byte, body string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func serveWebhook(h *WebhookHandler, target string, header http.Header, body string) int {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for key, values := range header {
+		r.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestWebhookHandlerValidation(t *testing.T) {
+	key := []byte("signature-key")
+	tests := []struct {
+		name   string
+		target string
+		header http.Header
+		body   string
+		want   int
+	}{
+		{name: "secret in query", target: "/hook?secret=s3cret", want: http.StatusOK},
+		{name: "secret in header", target: "/hook", header: http.Header{WebhookSecretHeader: {"s3cret"}}, want: http.StatusOK},
+		{name: "missing secret", target: "/hook", want: http.StatusUnauthorized},
+		{name: "wrong secret in query", target: "/hook?secret=wrong", want: http.StatusUnauthorized},
+		{name: "wrong secret in header", target: "/hook?secret=s3cret", header: http.Header{WebhookSecretHeader: {"wrong"}}, want: http.StatusUnauthorized},
+		{name: "signature", target: "/hook?secret=s3cret", header: http.Header{WebhookSignatureHeader: {signWebhook(key, webhookBody)}}, want: http.StatusOK},
+		{name: "signature with prefix", target: "/hook?secret=s3cret", header: http.Header{WebhookSignatureHeader: {"sha256=" + signWebhook(key, webhookBody)}}, want: http.StatusOK},
+		{name: "wrong signature", target: "/hook?secret=s3cret", header: http.Header{WebhookSignatureHeader: {signWebhook([]byte("other"), webhookBody)}}, want: http.StatusUnauthorized},
+		{name: "invalid signature", target: "/hook?secret=s3cret", header: http.Header{WebhookSignatureHeader: {"sha256=xyz"}}, want: http.StatusUnauthorized},
+		{name: "body too large", target: "/hook?secret=s3cret", body: `{"commonName":"` + strings.Repeat("x", 2048) + `"}`, want: http.StatusRequestEntityTooLarge},
+		{name: "invalid payload", target: "/hook?secret=s3cret", body: `{"commonName":"eneco-001-123456"}`, want: http.StatusBadRequest},
+		{name: "unknown display", target: "/hook?secret=s3cret", body: `{"commonName":"other","updateDataSet":{}}`, want: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewWebhookHandler("s3cret")
+			defer h.Close()
+			h.MaxBodySize = 1024
+			h.CommonNames = []string{"eneco-001-123456"}
+			if _, ok := test.header[WebhookSignatureHeader]; ok {
+				h.SignatureKey = key
+			}
+
+			body := test.body
+			if len(body) == 0 {
+				body = webhookBody
+			}
+
+			if code := serveWebhook(h, test.target, test.header, body); code != test.want {
+				t.Fatalf("status %d, want %d", code, test.want)
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerDispatch(t *testing.T) {
+	h := NewWebhookHandler("")
+	defer h.Close()
+
+	events := make(chan string, 3)
+	h.OnEvent = func(event WebhookEvent) { events <- "event " + event.CommonName }
+	h.OnThermostatInfo = func(commonName string, info ThermostatInfo) {
+		events <- fmt.Sprint("thermostat ", info.CurrentSetpoint)
+	}
+	h.OnPowerUsage = func(commonName string, usage PowerUsage) { events <- fmt.Sprint("power ", usage.Value) }
+	h.OnGasUsage = func(commonName string, usage GasUsage) { events <- "gas" }
+
+	if code := serveWebhook(h, "/hook", nil, webhookBody); code != http.StatusOK {
+		t.Fatalf("status %d, want %d", code, http.StatusOK)
+	}
+
+	// callbacks are called in order, only for the fragments which are present
+	for _, want := range []string{"event eneco-001-123456", "thermostat 2050", "power 420"} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %q", want)
+		}
+	}
+}
+
+func TestWebhookHandlerQueueFull(t *testing.T) {
+	h := NewWebhookHandler("")
+	defer h.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	h.OnEvent = func(WebhookEvent) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}
+
+	dropped := make(chan error, 1)
+	h.OnError = func(err error) {
+		select {
+		case dropped <- err:
+		default:
+		}
+	}
+
+	// the first event blocks the callback goroutine, the next events fill the queue
+	serveWebhook(h, "/hook", nil, webhookBody)
+	<-started
+	for i := 0; i < DefaultWebhookQueueSize; i++ {
+		serveWebhook(h, "/hook", nil, webhookBody)
+	}
+
+	if code := serveWebhook(h, "/hook", nil, webhookBody); code != http.StatusOK {
+		t.Fatalf("status %d, want %d", code, http.StatusOK)
+	}
+
+	select {
+	case err := <-dropped:
+		if !errors.Is(err, ErrWebhookQueueFull) {
+			t.Fatalf("OnError(%v), want %v", err, ErrWebhookQueueFull)
+		}
+	default:
+		t.Fatal("event was not dropped")
+	}
+}
+
+func TestWebhookHandlerClosed(t *testing.T) {
+	h := NewWebhookHandler("")
+	h.OnEvent = func(WebhookEvent) { t.Error("event dispatched after Close") }
+	h.Close()
+	h.Close()
+
+	if code := serveWebhook(h, "/hook", nil, webhookBody); code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookHandlerZeroValue(t *testing.T) {
+	h := &WebhookHandler{}
+	defer h.Close()
+
+	received := make(chan struct{}, 1)
+	h.OnEvent = func(WebhookEvent) { received <- struct{}{} }
+	if code := serveWebhook(h, "/hook", nil, webhookBody); code != http.StatusOK {
+		t.Fatalf("status %d, want %d", code, http.StatusOK)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("event of the zero value handler was not dispatched")
+	}
+}
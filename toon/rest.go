@@ -1,30 +1,51 @@
 package toon
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
 )
 
 var (
 	httpClient = &http.Client{Timeout: 10 * time.Second}
 )
 
-func test(url string, auth *auth.ToonAuthenticator) {
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", auth.Token.AccessToken))
-	resp, _ := httpClient.Do(req)
-	b, _ := ioutil.ReadAll(resp.Body)
-	fmt.Println(string(b))
+func get(endpoint string, params map[string]string, agreementID string, auth *auth.ToonAuthenticator, target interface{}, isRetry bool) *ErrorResponse {
+	return request(requestOptions{}, http.MethodGet, endpoint, params, agreementID, auth, nil, target, isRetry)
+}
+
+func post(endpoint string, params map[string]string, agreementID string, auth *auth.ToonAuthenticator, payload, target interface{}) *ErrorResponse {
+	return request(requestOptions{}, http.MethodPost, endpoint, params, agreementID, auth, payload, target, false)
+}
+
+func put(endpoint string, params map[string]string, agreementID string, auth *auth.ToonAuthenticator, payload, target interface{}) *ErrorResponse {
+	return request(requestOptions{}, http.MethodPut, endpoint, params, agreementID, auth, payload, target, false)
+}
+
+func del(endpoint string, params map[string]string, agreementID string, auth *auth.ToonAuthenticator, target interface{}) *ErrorResponse {
+	return request(requestOptions{}, http.MethodDelete, endpoint, params, agreementID, auth, nil, target, false)
 }
 
-func get(url string, auth *auth.ToonAuthenticator, target interface{}, isRetry bool) *ErrorResponse {
+// requestOptions contains the settings of a request which are not sent to the Toon API
+type requestOptions struct {
+	// ctx stops waiting for rate limit budget, context.Background when nil
+	ctx context.Context
+	// background requests can not use the BackgroundReserve of the rate limiter
+	background bool
+}
+
+// request sends a request to the Toon API, the payload is send as JSON when not nil and
+// the response is parsed into target when not nil
+func request(options requestOptions, method, endpoint string, params map[string]string, agreementID string, auth *auth.ToonAuthenticator, payload, target interface{}, isRetry bool) *ErrorResponse {
 	// if currently authenticating wait 10 seconds and check every 100ms
 	if auth.IsAuthenticating {
 		for i := 0; i < 100; i++ {
@@ -35,47 +56,127 @@ func get(url string, auth *auth.ToonAuthenticator, target interface{}, isRetry b
 		}
 	}
 
-	req, _ := http.NewRequest("GET", url, nil)
+	resolved, err := resolveEndpoint(auth.TenantID(), endpoint)
+	if err != nil {
+		telemetry.Logger().Warn("toon api request not send", "method", method, "endpoint", endpointTemplate(endpoint, agreementID), "error", err)
+		return newErrorResponse(err, "Unsupported by provider")
+	}
+
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return newErrorResponse(err, "Unable to create JSON")
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	uri := constructEndpointURI(resolved, params, agreementID)
+	req, _ := http.NewRequest(method, uri, body)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", auth.Token.AccessToken))
+
+	info := telemetry.RequestInfo{
+		Method:   req.Method,
+		Endpoint: endpointTemplate(resolved, agreementID),
+		URL:      telemetry.RedactURL(uri),
+		Header:   telemetry.RedactHeader(req.Header),
+	}
+	tracer := telemetry.ActiveTracer()
+	logger := telemetry.Logger()
+
+	if limiter := rateLimiter.Load(); limiter != nil {
+		ctx := options.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if err := limiter.wait(ctx, callKind(req.Method), options.background); err != nil {
+			telemetry.TraceRejected(tracer, info, telemetry.RejectRateLimit, err)
+			logger.Warn("toon api request not send", "method", info.Method, "endpoint", info.Endpoint, "error", err)
+			return newErrorResponse(err, "Rate limited")
+		}
+	}
+
+	breaker := circuitBreaker.Load()
+	var admitted admission
+	if breaker != nil {
+		if admitted, err = breaker.allow(req.URL.Host); err != nil {
+			telemetry.TraceRejected(tracer, info, telemetry.RejectCircuitOpen, err)
+			logger.Warn("toon api request not send", "method", info.Method, "endpoint", info.Endpoint, "error", err)
+			return newErrorResponse(err, "Circuit open")
+		}
+	}
+
+	// the latency starts after waiting for the rate limiter
+	info.Start = time.Now()
+	tracer.RequestStart(info)
+	logger.Debug("toon api request", "method", info.Method, "endpoint", info.Endpoint, "url", info.URL)
+
 	resp, err := httpClient.Do(req)
-	if resp.StatusCode == http.StatusOK {
+	if breaker != nil {
+		breaker.report(req.URL.Host, admitted, err == nil && resp.StatusCode < http.StatusInternalServerError)
+	}
+
+	result := telemetry.RequestResult{Duration: time.Since(info.Start), Err: err}
+	if err != nil {
+		tracer.RequestEnd(info, result)
+		logger.Warn("toon api request failed", "method", info.Method, "endpoint", info.Endpoint, "duration", result.Duration, "error", err)
+		return newErrorResponse(err, "Request failed")
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	tracer.RequestEnd(info, result)
+	logger.Debug("toon api response", "method", info.Method, "endpoint", info.Endpoint, "status", resp.StatusCode, "duration", result.Duration)
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		if !isRetry {
+			telemetry.TraceCacheLookup(tracer, telemetry.CacheAccessToken, true)
+		}
+
+		if target == nil {
+			return nil
+		}
+
 		err = json.NewDecoder(resp.Body).Decode(&target)
-		if err == nil {
+		if err == nil || err == io.EOF {
 			return nil
 		}
 
-		return &ErrorResponse{Fault: Fault{Faultstring: fmt.Sprintf("%v", err), Detail: FaultDetail{Errorcode: "Unable to parse JSON"}}}
+		logger.Warn("unable to parse toon api response", "endpoint", info.Endpoint, "error", err)
+		return newErrorResponse(err, "Unable to parse JSON")
 	}
 
 	// Status not ok
 	errorResponse := &ErrorResponse{}
 	err = json.NewDecoder(resp.Body).Decode(errorResponse)
-	if err != nil {
+	if err == nil {
 		if !isRetry && resp.StatusCode == http.StatusUnauthorized && errorResponse.Fault.Faultstring == "Access Token expired" {
+			telemetry.TraceCacheLookup(tracer, telemetry.CacheAccessToken, false)
+			tracer.RequestRetry(info, 1, errorResponse.Fault.Faultstring)
+			logger.Info("toon access token expired, refreshing token and retrying request", "endpoint", info.Endpoint)
 			auth.StartRefreshToken()
-			return get(url, auth, target, true)
+			return request(options, method, endpoint, params, agreementID, auth, payload, target, true)
 		}
 
+		logger.Warn("toon api returned an error", "endpoint", info.Endpoint, "status", resp.StatusCode, "fault", errorResponse.Fault.Faultstring)
 		return errorResponse
 	}
 
+	logger.Warn("toon api returned an error", "endpoint", info.Endpoint, "status", resp.StatusCode)
 	return &ErrorResponse{Fault: Fault{Faultstring: fmt.Sprintf("%v", err), Detail: FaultDetail{Errorcode: fmt.Sprintf("Statuscode: %v", resp.StatusCode)}}}
 }
 
-func post() {
-
-}
-
-func put() {
-
-}
-
-func delete() {
-
-}
-
+// constructEndpointURI creates the URI for an endpoint, params are added to the query except when
+// the endpoint contains a {key} placeholder for the param, e.g. /devices/{devUUID}
 func constructEndpointURI(endpoint string, params map[string]string, agreementID string) string {
+	template := endpoint
+	for k, v := range params {
+		endpoint = strings.ReplaceAll(endpoint, fmt.Sprintf("{%s}", k), url.PathEscape(v))
+	}
+
 	uri := apiEndpoint
 	if len(agreementID) == 0 {
 		uri = fmt.Sprintf("%s%s", uri, endpoint)
@@ -85,6 +186,10 @@ func constructEndpointURI(endpoint string, params map[string]string, agreementID
 
 	if params != nil && len(params) > 0 {
 		for k, v := range params {
+			if isPathParam(k, template) {
+				continue
+			}
+
 			prefix := "?"
 			if strings.Contains(uri, "?") {
 				prefix = "&"
@@ -96,3 +201,17 @@ func constructEndpointURI(endpoint string, params map[string]string, agreementID
 
 	return uri
 }
+
+// isPathParam returns true when a param is used as placeholder in the endpoint template
+func isPathParam(key, endpoint string) bool {
+	return strings.Contains(endpoint, fmt.Sprintf("{%s}", key))
+}
+
+// endpointTemplate returns the endpoint as used in logging and metrics, without any ids
+func endpointTemplate(endpoint, agreementID string) string {
+	if len(agreementID) == 0 {
+		return endpoint
+	}
+
+	return "/{agreementId}" + endpoint
+}
@@ -0,0 +1,102 @@
+package toon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+// rewriteTransport sends the requests for the Toon API to a test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newTestServer starts a test server which receives all requests made by the package
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(handler)
+	target, _ := url.Parse(server.URL)
+
+	client := httpClient
+	httpClient = &http.Client{Timeout: 5 * time.Second, Transport: rewriteTransport{target: target}}
+	t.Cleanup(func() {
+		httpClient = client
+		server.Close()
+	})
+
+	return server
+}
+
+// recordedRequest is a request received by the server of recordRequests, Path is escaped
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   string
+}
+
+// recordRequests starts a test server which records all requests and answers them with the
+// response for their escaped path, or an empty JSON object
+func recordRequests(t *testing.T, responses map[string]string) *[]recordedRequest {
+	requests := &[]recordedRequest{}
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, recordedRequest{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.Query(), Body: string(body)})
+		if response, ok := responses[r.URL.EscapedPath()]; ok {
+			w.Write([]byte(response))
+			return
+		}
+
+		w.Write([]byte(`{}`))
+	})
+
+	return requests
+}
+
+func TestExpiredTokenIsRefreshed(t *testing.T) {
+	requests := 0
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			w.Write([]byte(`{"access_token":"new","expires_in":"3600","refresh_token":"refresh"}`))
+		case "/toon/v3/1/status":
+			requests++
+			if r.Header.Get("Authorization") != "Bearer new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"fault":{"faultstring":"Access Token expired"}}`))
+				return
+			}
+
+			w.Write([]byte(`{"serverTime":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tokenURL := auth.TokenURL
+	auth.TokenURL = server.URL + "/token"
+	defer func() { auth.TokenURL = tokenURL }()
+
+	// nobody reads the authentication events, the refresh must not block on them
+	authenticator := auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0)
+	authenticator.Token.AccessToken = "old"
+
+	status, err := GetStatus(authenticator, "1")
+	if err != nil {
+		t.Fatalf("GetStatus() = %v", err)
+	}
+
+	if status.ServerTime != 1 || requests != 2 {
+		t.Fatalf("got server time %d after %d requests, want 1 after 2", status.ServerTime, requests)
+	}
+}
@@ -0,0 +1,229 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// ErrScenePartiallyApplied is returned when switching one or more devices of a scene failed
+var ErrScenePartiallyApplied = errors.New("scene partially applied")
+
+// Scene is a named set of devices with their desired state, scenes are stored client side
+type Scene struct {
+	Name string `json:"name"`
+	// Devices maps the DevUUID of a device to its desired state, true is on
+	Devices map[string]bool `json:"devices"`
+}
+
+// SceneDeviceResult is the result of applying a scene to one device
+type SceneDeviceResult struct {
+	DevUUID  string `json:"devUUID"`
+	Name     string `json:"name"`
+	Previous bool   `json:"previous"`
+	Desired  bool   `json:"desired"`
+	// Changed is true when the device was switched
+	Changed bool `json:"changed"`
+	// RolledBack is true when the device was switched back to its previous state
+	RolledBack bool           `json:"rolledBack"`
+	Err        *ErrorResponse `json:"error,omitempty"`
+}
+
+// SceneResult is the result of applying a scene
+type SceneResult struct {
+	Scene   string              `json:"scene"`
+	Devices []SceneDeviceResult `json:"devices"`
+}
+
+// Failed returns the results of the devices which could not be switched
+func (r SceneResult) Failed() []SceneDeviceResult {
+	failed := []SceneDeviceResult{}
+	for _, device := range r.Devices {
+		if device.Err != nil {
+			failed = append(failed, device)
+		}
+	}
+
+	return failed
+}
+
+// CaptureScene creates a scene from the current state of the given devices, all
+// devices are captured when no DevUUIDs are given
+func CaptureScene(auth *auth.ToonAuthenticator, agreementID, name string, devUUIDs ...string) (*Scene, *ErrorResponse) {
+	devices, err := GetDevices(auth, agreementID)
+	if err != nil {
+		return nil, err
+	}
+
+	scene := &Scene{Name: name, Devices: map[string]bool{}}
+	if len(devUUIDs) == 0 {
+		for _, device := range *devices {
+			scene.Devices[device.DevUUID] = device.IsOn()
+		}
+
+		return scene, nil
+	}
+
+	for _, devUUID := range devUUIDs {
+		device, ok := devices.Get(devUUID)
+		if !ok {
+			return nil, newErrorResponse(fmt.Errorf("device %v not found", devUUID), "Unknown device")
+		}
+
+		scene.Devices[devUUID] = device.IsOn()
+	}
+
+	return scene, nil
+}
+
+// ApplyScene switches the devices of a scene to their desired state, devices which are already in the desired
+// state are not written. When switching a device fails the other devices are still switched, or with rollback
+// all devices which were switched are switched back to their previous state. The result contains the outcome
+// per device, the returned error wraps ErrScenePartiallyApplied when one or more devices failed.
+func ApplyScene(auth *auth.ToonAuthenticator, agreementID string, scene Scene, rollback bool) (*SceneResult, *ErrorResponse) {
+	devices, err := GetDevices(auth, agreementID)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyScene(auth, agreementID, devices, scene, rollback)
+}
+
+// SwitchAllScene is the name of the scene applied by SwitchAll
+const SwitchAllScene = "switch all"
+
+// SwitchAll switches all devices in the switch all group on or off, like the switch all function of the Toon.
+// Devices are added to the group with SetDeviceInSwitchAll, devices which are switch locked are skipped.
+// The result and rollback are the same as for ApplyScene.
+func SwitchAll(auth *auth.ToonAuthenticator, agreementID string, on, rollback bool) (*SceneResult, *ErrorResponse) {
+	devices, err := GetDevices(auth, agreementID)
+	if err != nil {
+		return nil, err
+	}
+
+	scene := Scene{Name: SwitchAllScene, Devices: map[string]bool{}}
+	for _, device := range devices.InSwitchAll() {
+		if !device.IsSwitchLocked() {
+			scene.Devices[device.DevUUID] = on
+		}
+	}
+
+	return applyScene(auth, agreementID, devices, scene, rollback)
+}
+
+func applyScene(auth *auth.ToonAuthenticator, agreementID string, devices *DeviceConfigs, scene Scene, rollback bool) (*SceneResult, *ErrorResponse) {
+	devUUIDs := make([]string, 0, len(scene.Devices))
+	for devUUID := range scene.Devices {
+		devUUIDs = append(devUUIDs, devUUID)
+	}
+	sort.Strings(devUUIDs)
+
+	result := &SceneResult{Scene: scene.Name, Devices: []SceneDeviceResult{}}
+	failed := false
+	for _, devUUID := range devUUIDs {
+		desired := scene.Devices[devUUID]
+		device, ok := devices.Get(devUUID)
+		if !ok {
+			failed = true
+			result.Devices = append(result.Devices, SceneDeviceResult{DevUUID: devUUID, Desired: desired, Err: newErrorResponse(fmt.Errorf("device %v not found", devUUID), "Unknown device")})
+			if rollback {
+				break
+			}
+
+			continue
+		}
+
+		deviceResult := SceneDeviceResult{DevUUID: devUUID, Name: device.Name, Previous: device.IsOn(), Desired: desired}
+		if device.IsOn() != desired {
+			device.CurrentState = formatFlag(desired)
+			if _, err := UpdateDevice(auth, agreementID, device); err != nil {
+				deviceResult.Err = err
+				failed = true
+			} else {
+				deviceResult.Changed = true
+			}
+		}
+
+		result.Devices = append(result.Devices, deviceResult)
+		if failed && rollback {
+			break
+		}
+	}
+
+	if !failed {
+		return result, nil
+	}
+
+	if rollback {
+		rollbackScene(auth, agreementID, devices, result)
+	}
+
+	errs := []error{ErrScenePartiallyApplied}
+	for _, device := range result.Failed() {
+		errs = append(errs, fmt.Errorf("%v: %w", device.DevUUID, device.Err))
+	}
+
+	telemetry.Logger().Warn("toon scene partially applied", "scene", scene.Name, "failed", len(result.Failed()), "rollback", rollback)
+	return result, newErrorResponse(errors.Join(errs...), "Scene partially applied")
+}
+
+// rollbackScene switches all changed devices back to their previous state
+func rollbackScene(auth *auth.ToonAuthenticator, agreementID string, devices *DeviceConfigs, result *SceneResult) {
+	for i, deviceResult := range result.Devices {
+		if !deviceResult.Changed {
+			continue
+		}
+
+		device, _ := devices.Get(deviceResult.DevUUID)
+		device.CurrentState = formatFlag(deviceResult.Previous)
+		if _, err := UpdateDevice(auth, agreementID, device); err != nil {
+			telemetry.Logger().Warn("unable to roll back toon scene device", "scene", result.Scene, "dev_uuid", deviceResult.DevUUID, "error", err)
+			result.Devices[i].Err = err
+			continue
+		}
+
+		result.Devices[i].RolledBack = true
+	}
+}
+
+// SceneStore stores scenes by name
+type SceneStore interface {
+	Save(scene Scene) error
+	Delete(name string) error
+	List() ([]Scene, error)
+}
+
+// FileSceneStore is a SceneStore which keeps scenes in a JSON file
+type FileSceneStore struct {
+	Path  string
+	store fileStore[Scene]
+}
+
+// NewFileSceneStore creates a SceneStore which keeps scenes in the file at path
+func NewFileSceneStore(path string) *FileSceneStore {
+	return &FileSceneStore{Path: path}
+}
+
+// Save implements SceneStore
+func (s *FileSceneStore) Save(scene Scene) error {
+	return s.store.save(s.Path, scene.Name, scene)
+}
+
+// Delete implements SceneStore
+func (s *FileSceneStore) Delete(name string) error {
+	return s.store.delete(s.Path, name)
+}
+
+// List implements SceneStore
+func (s *FileSceneStore) List() ([]Scene, error) {
+	scenes, err := s.store.list(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(scenes, func(i, j int) bool { return scenes[i].Name < scenes[j].Name })
+	return scenes, nil
+}
@@ -0,0 +1,157 @@
+package toon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+// deviceServer is a fake Toon with smart plugs, switching the devices in fail returns an error
+type deviceServer struct {
+	mu      sync.Mutex
+	devices DeviceConfigs
+	fail    map[string]bool
+	updates []string
+}
+
+func newDeviceServer(t *testing.T, devices DeviceConfigs, fail ...string) *deviceServer {
+	s := &deviceServer{devices: devices, fail: map[string]bool{}}
+	for _, devUUID := range fail {
+		s.fail[devUUID] = true
+	}
+
+	newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if r.URL.Path == "/toon/v3/1/devices" {
+			json.NewEncoder(w).Encode(s.devices)
+			return
+		}
+
+		devUUID := strings.TrimPrefix(r.URL.Path, "/toon/v3/1/devices/")
+		for i, device := range s.devices {
+			if device.DevUUID != devUUID {
+				continue
+			}
+
+			if r.Method == http.MethodPut {
+				if s.fail[devUUID] {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				json.NewDecoder(r.Body).Decode(&s.devices[i])
+				s.updates = append(s.updates, devUUID+"="+s.devices[i].CurrentState)
+			}
+
+			json.NewEncoder(w).Encode(s.devices[i])
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+
+	return s
+}
+
+func testDevices() DeviceConfigs {
+	return DeviceConfigs{
+		{DevUUID: "a", Name: "Lamp", CurrentState: "0", InSwitchAll: 1},
+		{DevUUID: "b", Name: "Tv", CurrentState: "1", InSwitchAll: 1},
+		{DevUUID: "c", Name: "Fridge", CurrentState: "0", InSwitchAll: 1, SwitchLocked: "1"},
+		{DevUUID: "d", Name: "Heater", CurrentState: "0"},
+	}
+}
+
+func sceneUpdates(s *deviceServer) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.updates, " ")
+}
+
+func TestApplyScene(t *testing.T) {
+	tests := []struct {
+		name     string
+		devices  map[string]bool
+		fail     []string
+		rollback bool
+		// updates are the switched devices in order, rolled back devices are switched twice
+		updates    string
+		failed     []string
+		rolledBack []string
+	}{
+		{name: "only changed devices are switched", updates: "a=1 c=1 d=1"},
+		{name: "continue after a failure", fail: []string{"c"}, updates: "a=1 d=1", failed: []string{"c"}},
+		{name: "rollback after a failure", fail: []string{"c"}, rollback: true, updates: "a=1 a=0", failed: []string{"c"}, rolledBack: []string{"a"}},
+		{
+			name:       "unknown device",
+			devices:    map[string]bool{"a": true, "bb": true, "d": true},
+			rollback:   true,
+			updates:    "a=1 a=0",
+			failed:     []string{"bb"},
+			rolledBack: []string{"a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newDeviceServer(t, testDevices(), test.fail...)
+			scene := Scene{Name: "evening", Devices: test.devices}
+			if scene.Devices == nil {
+				scene.Devices = map[string]bool{"a": true, "b": true, "c": true, "d": true}
+			}
+
+			result, err := ApplyScene(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0), "1", scene, test.rollback)
+			if len(test.failed) == 0 && err != nil {
+				t.Fatalf("ApplyScene() = %v", err)
+			}
+
+			if len(test.failed) > 0 && (err == nil || !errors.Is(err.Err, ErrScenePartiallyApplied)) {
+				t.Fatalf("ApplyScene() = %v, want %v", err, ErrScenePartiallyApplied)
+			}
+
+			if got := sceneUpdates(server); got != test.updates {
+				t.Fatalf("updates %q, want %q", got, test.updates)
+			}
+
+			failed, rolledBack := []string{}, []string{}
+			for _, device := range result.Devices {
+				if device.Err != nil {
+					failed = append(failed, device.DevUUID)
+				}
+
+				if device.RolledBack {
+					rolledBack = append(rolledBack, device.DevUUID)
+				}
+			}
+
+			if strings.Join(failed, ",") != strings.Join(test.failed, ",") || strings.Join(rolledBack, ",") != strings.Join(test.rolledBack, ",") {
+				t.Fatalf("failed %v and rolled back %v, want %v and %v", failed, rolledBack, test.failed, test.rolledBack)
+			}
+		})
+	}
+}
+
+func TestSwitchAll(t *testing.T) {
+	server := newDeviceServer(t, testDevices())
+
+	// the locked fridge and the heater outside the group are not switched, the lamp is already off
+	result, err := SwitchAll(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0), "1", false, true)
+	if err != nil {
+		t.Fatalf("SwitchAll() = %v", err)
+	}
+
+	if got := sceneUpdates(server); got != "b=0" {
+		t.Fatalf("updates %q, want %q", got, "b=0")
+	}
+
+	if result.Scene != SwitchAllScene || len(result.Devices) != 2 {
+		t.Fatalf("SwitchAll() = %+v, want results for the lamp and tv", result)
+	}
+}
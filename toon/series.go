@@ -0,0 +1,349 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrResampleFiner is returned when a series is resampled to a finer interval than it has
+var ErrResampleFiner = errors.New("cannot resample to a finer interval")
+
+// ErrInvalidInterval is returned when a series is resampled from or to an unknown interval
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// FlowStep is the time between the values of the 5 minute flow data
+const FlowStep = 5 * time.Minute
+
+// Aggregation defines how the values in an interval are combined when resampling
+type Aggregation int
+
+// Aggregations
+const (
+	// AggregateSum adds the values, use it for quantities such as consumed energy or gas
+	AggregateSum Aggregation = iota
+	// AggregateAverage averages the values, use it for rates such as power
+	AggregateAverage
+	AggregateMin
+	AggregateMax
+)
+
+var aggregations = [...]string{
+	"sum",
+	"average",
+	"min",
+	"max",
+}
+
+// String() function will return the name of an aggregation
+func (a Aggregation) String() string {
+	return aggregations[a]
+}
+
+// GapFill defines how missing points are filled by FillGaps
+type GapFill int
+
+// Gap fill modes
+const (
+	// GapFillZero fills missing points with 0
+	GapFillZero GapFill = iota
+	// GapFillPrevious repeats the value of the previous point
+	GapFillPrevious
+	// GapFillLinear interpolates between the surrounding points
+	GapFillLinear
+)
+
+var gapFills = [...]string{
+	"zero",
+	"previous",
+	"linear",
+}
+
+// String() function will return the name of a gap fill mode
+func (g GapFill) String() string {
+	return gapFills[g]
+}
+
+// Point is a value at a point in time, the time is the start of the interval of the value
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Series is a time series of consumption or production data ordered by time, Interval is
+// IntervalNone for the 5 minute flow data
+type Series struct {
+	Unit     string   `json:"unit"`
+	Interval Interval `json:"interval"`
+	Points   []Point  `json:"points"`
+}
+
+// NewSeries creates a series from points, the points are ordered by time
+func NewSeries(unit string, interval Interval, points []Point) Series {
+	sorted := append([]Point{}, points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	return Series{Unit: unit, Interval: interval, Points: sorted}
+}
+
+// Series returns the filled interval of the flow data as series, the 5 minute flow data
+// which the API returns as hours is recognized and gets IntervalNone
+func (f FlowData) Series() Series {
+	intervals := []Interval{IntervalHours, IntervalDays, IntervalWeeks, IntervalMonths, IntervalYears}
+	for i, values := range [][]FlowDataValue{f.Hours, f.Days, f.Weeks, f.Months, f.Years} {
+		if len(values) == 0 {
+			continue
+		}
+
+		points := make([]Point, len(values))
+		for j, value := range values {
+			points[j] = Point{Time: time.UnixMilli(value.Timestamp), Value: value.Value}
+		}
+
+		series := NewSeries(values[0].Unit, intervals[i], points)
+		if series.Interval == IntervalHours && len(points) > 1 && series.Points[1].Time.Sub(series.Points[0].Time) < time.Hour {
+			series.Interval = IntervalNone
+		}
+
+		return series
+	}
+
+	return Series{Interval: IntervalHours, Points: []Point{}}
+}
+
+// Series returns the filled interval of the graph data as series of the peak and off-peak tariff combined
+func (e ElectricityGraphData) Series() Series {
+	return e.series(func(g GraphData) float64 { return g.Peak + g.OffPeak })
+}
+
+// TariffSeries returns the filled interval of the graph data as separate series for the peak and off-peak tariff
+func (e ElectricityGraphData) TariffSeries() (Series, Series) {
+	return e.series(func(g GraphData) float64 { return g.Peak }), e.series(func(g GraphData) float64 { return g.OffPeak })
+}
+
+func (e ElectricityGraphData) series(value func(g GraphData) float64) Series {
+	intervals := []Interval{IntervalHours, IntervalDays, IntervalWeeks, IntervalMonths, IntervalYears}
+	for i, values := range [][]GraphData{e.Hours, e.Days, e.Weeks, e.Months, e.Years} {
+		if len(values) > 0 {
+			return GraphDataSeries(values, intervals[i], value)
+		}
+	}
+
+	return Series{Interval: IntervalHours, Points: []Point{}}
+}
+
+// GraphDataSeries creates a series from graph data values, value selects the value of a point
+func GraphDataSeries(values []GraphData, interval Interval, value func(g GraphData) float64) Series {
+	unit := ""
+	points := make([]Point, len(values))
+	for i, v := range values {
+		unit = v.Unit
+		points[i] = Point{Time: time.UnixMilli(v.Timestamp), Value: value(v)}
+	}
+
+	return NewSeries(unit, interval, points)
+}
+
+// All returns an iterator over the time and value of the points, it can be used with range over func
+func (s Series) All() func(yield func(time.Time, float64) bool) {
+	return func(yield func(time.Time, float64) bool) {
+		for _, p := range s.Points {
+			if !yield(p.Time, p.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of points
+func (s Series) Len() int {
+	return len(s.Points)
+}
+
+// Sum returns the sum of all values
+func (s Series) Sum() float64 {
+	sum := 0.0
+	for _, p := range s.Points {
+		sum += p.Value
+	}
+
+	return sum
+}
+
+// Average returns the average of all values, 0 for an empty series
+func (s Series) Average() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+
+	return s.Sum() / float64(len(s.Points))
+}
+
+// Min returns the point with the lowest value, false for an empty series
+func (s Series) Min() (Point, bool) {
+	return s.extreme(func(a, b float64) bool { return a < b })
+}
+
+// Max returns the point with the highest value, false for an empty series
+func (s Series) Max() (Point, bool) {
+	return s.extreme(func(a, b float64) bool { return a > b })
+}
+
+func (s Series) extreme(better func(a, b float64) bool) (Point, bool) {
+	if len(s.Points) == 0 {
+		return Point{}, false
+	}
+
+	best := s.Points[0]
+	for _, p := range s.Points[1:] {
+		if better(p.Value, best.Value) {
+			best = p
+		}
+	}
+
+	return best, true
+}
+
+// Resample combines the points into a coarser interval using the aggregation, intervals start
+// at midnight in Amsterdam and weeks start on Monday
+func (s Series) Resample(interval Interval, aggregation Aggregation) (Series, error) {
+	if !interval.valid() {
+		return Series{}, fmt.Errorf("%w: unknown interval %d", ErrInvalidInterval, interval)
+	}
+
+	if !s.Interval.valid() {
+		return Series{}, fmt.Errorf("%w: series has unknown interval %d", ErrInvalidInterval, s.Interval)
+	}
+
+	if interval < s.Interval {
+		return Series{}, fmt.Errorf("%w: %v to %v", ErrResampleFiner, s.Interval, interval)
+	}
+
+	resampled := Series{Unit: s.Unit, Interval: interval, Points: []Point{}}
+	values := []float64{}
+	var bucket time.Time
+	for i, p := range s.Points {
+		start := interval.truncate(p.Time)
+		if i > 0 && !start.Equal(bucket) {
+			resampled.Points = append(resampled.Points, Point{Time: bucket, Value: aggregate(values, aggregation)})
+			values = values[:0]
+		}
+
+		bucket = start
+		values = append(values, p.Value)
+	}
+
+	if len(values) > 0 {
+		resampled.Points = append(resampled.Points, Point{Time: bucket, Value: aggregate(values, aggregation)})
+	}
+
+	return resampled, nil
+}
+
+func aggregate(values []float64, aggregation Aggregation) float64 {
+	result := values[0]
+	switch aggregation {
+	case AggregateSum, AggregateAverage:
+		result = 0
+		for _, v := range values {
+			result += v
+		}
+
+		if aggregation == AggregateAverage {
+			result /= float64(len(values))
+		}
+	case AggregateMin:
+		for _, v := range values {
+			result = math.Min(result, v)
+		}
+	case AggregateMax:
+		for _, v := range values {
+			result = math.Max(result, v)
+		}
+	}
+
+	return result
+}
+
+// FillGaps adds the missing points between the first and last point of the series, one
+// point per interval, using the gap fill mode for their values. The points are moved to the
+// start of their interval first, when several points fall in the same interval the last is
+// kept, use Resample to combine them instead
+func (s Series) FillGaps(fill GapFill) Series {
+	aligned := []Point{}
+	for _, p := range s.Points {
+		p.Time = s.Interval.truncate(p.Time)
+		if len(aligned) > 0 && aligned[len(aligned)-1].Time.Equal(p.Time) {
+			aligned[len(aligned)-1] = p
+			continue
+		}
+
+		aligned = append(aligned, p)
+	}
+
+	filled := Series{Unit: s.Unit, Interval: s.Interval, Points: []Point{}}
+	for i, p := range aligned {
+		if i > 0 {
+			prev := aligned[i-1]
+			for t := s.Interval.next(prev.Time); t.Before(p.Time); t = s.Interval.next(t) {
+				value := 0.0
+				switch fill {
+				case GapFillPrevious:
+					value = prev.Value
+				case GapFillLinear:
+					ratio := float64(t.Sub(prev.Time)) / float64(p.Time.Sub(prev.Time))
+					value = prev.Value + (p.Value-prev.Value)*ratio
+				}
+
+				filled.Points = append(filled.Points, Point{Time: t, Value: value})
+			}
+		}
+
+		filled.Points = append(filled.Points, p)
+	}
+
+	return filled
+}
+
+func (i Interval) valid() bool {
+	return i >= IntervalNone && i <= IntervalYears
+}
+
+// truncate returns the start of the interval containing t, in Amsterdam time
+func (i Interval) truncate(t time.Time) time.Time {
+	t = t.In(Amsterdam)
+	switch i {
+	case IntervalNone:
+		return t.Truncate(FlowStep)
+	case IntervalHours:
+		// the offset of Amsterdam is whole hours, truncating keeps the repeated hour of the DST change apart
+		return t.Truncate(time.Hour)
+	case IntervalDays:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, Amsterdam)
+	case IntervalWeeks:
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, Amsterdam)
+	case IntervalMonths:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, Amsterdam)
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, Amsterdam)
+	}
+}
+
+// next returns the start of the interval after the interval starting at t
+func (i Interval) next(t time.Time) time.Time {
+	switch i {
+	case IntervalNone:
+		return t.Add(FlowStep)
+	case IntervalHours:
+		return t.Add(time.Hour)
+	case IntervalDays:
+		return t.In(Amsterdam).AddDate(0, 0, 1)
+	case IntervalWeeks:
+		return t.In(Amsterdam).AddDate(0, 0, 7)
+	case IntervalMonths:
+		return t.In(Amsterdam).AddDate(0, 1, 0)
+	default:
+		return t.In(Amsterdam).AddDate(1, 0, 0)
+	}
+}
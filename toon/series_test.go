@@ -0,0 +1,185 @@
+package toon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func points(start time.Time, step time.Duration, values ...float64) []Point {
+	p := make([]Point, len(values))
+	for i, v := range values {
+		p[i] = Point{Time: start.Add(time.Duration(i) * step), Value: v}
+	}
+
+	return p
+}
+
+func values(s Series) []float64 {
+	v := []float64{}
+	for _, p := range s.Points {
+		v = append(v, p.Value)
+	}
+
+	return v
+}
+
+func TestSeriesResample(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, Amsterdam)
+	flows := NewSeries("W", IntervalNone, points(start.Add(50*time.Minute), FlowStep, 1, 2, 3, 4, 5, 6))
+	days := NewSeries("kWh", IntervalDays, points(start, 24*time.Hour, 1, 2, 3, 4, 5, 6, 7, 8))
+
+	tests := []struct {
+		name        string
+		series      Series
+		interval    Interval
+		aggregation Aggregation
+		want        []float64
+	}{
+		// 00:50 and 00:55 are in the first hour, 01:00 until 01:15 in the second
+		{"flows to hours sum", flows, IntervalHours, AggregateSum, []float64{3, 18}},
+		{"flows to hours average", flows, IntervalHours, AggregateAverage, []float64{1.5, 4.5}},
+		{"flows to hours min", flows, IntervalHours, AggregateMin, []float64{1, 3}},
+		{"flows to hours max", flows, IntervalHours, AggregateMax, []float64{2, 6}},
+		// Monday 4 March until Sunday 10 March is one week
+		{"days to weeks", days, IntervalWeeks, AggregateSum, []float64{28, 8}},
+		{"days to months", days, IntervalMonths, AggregateMax, []float64{8}},
+		{"same interval", days, IntervalDays, AggregateSum, []float64{1, 2, 3, 4, 5, 6, 7, 8}},
+		{"empty", Series{Interval: IntervalHours}, IntervalDays, AggregateSum, []float64{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.series.Resample(test.interval, test.aggregation)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(values(got), test.want) || got.Interval != test.interval {
+				t.Fatalf("Resample() = %v %v, want %v", got.Interval, values(got), test.want)
+			}
+		})
+	}
+
+	if _, err := days.Resample(IntervalHours, AggregateSum); !errors.Is(err, ErrResampleFiner) {
+		t.Fatalf("Resample() = %v, want %v", err, ErrResampleFiner)
+	}
+
+	for _, interval := range []Interval{0, IntervalYears + 1} {
+		if _, err := days.Resample(interval, AggregateSum); !errors.Is(err, ErrInvalidInterval) {
+			t.Fatalf("Resample(%d) = %v, want %v", interval, err, ErrInvalidInterval)
+		}
+
+		series := Series{Interval: interval, Points: days.Points}
+		if _, err := series.Resample(IntervalYears, AggregateSum); !errors.Is(err, ErrInvalidInterval) {
+			t.Fatalf("Resample() of interval %d = %v, want %v", interval, err, ErrInvalidInterval)
+		}
+	}
+}
+
+func TestSeriesResampleDST(t *testing.T) {
+	// the clocks go back from 03:00 to 02:00 on 27 October 2024, which gives two hours starting at 02:00
+	start := time.Date(2024, 10, 27, 1, 0, 0, 0, Amsterdam)
+	series := NewSeries("W", IntervalNone, points(start, 30*time.Minute, 1, 1, 2, 2, 3, 3, 4, 4))
+	hours, _ := series.Resample(IntervalHours, AggregateSum)
+	if want := []float64{2, 4, 6, 8}; !reflect.DeepEqual(values(hours), want) {
+		t.Fatalf("Resample() = %v, want %v", values(hours), want)
+	}
+
+	days, _ := series.Resample(IntervalDays, AggregateSum)
+	if len(days.Points) != 1 || !days.Points[0].Time.Equal(time.Date(2024, 10, 27, 0, 0, 0, 0, Amsterdam)) {
+		t.Fatalf("Resample() = %v", days.Points)
+	}
+}
+
+func TestSeriesFillGaps(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, Amsterdam)
+	series := NewSeries("W", IntervalNone, []Point{{start, 10}, {start.Add(15 * time.Minute), 40}, {start.Add(20 * time.Minute), 0}})
+
+	tests := []struct {
+		fill GapFill
+		want []float64
+	}{
+		{GapFillZero, []float64{10, 0, 0, 40, 0}},
+		{GapFillPrevious, []float64{10, 10, 10, 40, 0}},
+		{GapFillLinear, []float64{10, 20, 30, 40, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fill.String(), func(t *testing.T) {
+			filled := series.FillGaps(test.fill)
+			if !reflect.DeepEqual(values(filled), test.want) {
+				t.Fatalf("FillGaps() = %v, want %v", values(filled), test.want)
+			}
+
+			for i, p := range filled.Points {
+				if want := start.Add(time.Duration(i) * FlowStep); !p.Time.Equal(want) {
+					t.Fatalf("point %d at %v, want %v", i, p.Time, want)
+				}
+			}
+		})
+	}
+
+	months := NewSeries("m3", IntervalMonths, []Point{{time.Date(2024, 1, 1, 0, 0, 0, 0, Amsterdam), 1}, {time.Date(2024, 4, 1, 0, 0, 0, 0, Amsterdam), 4}})
+	if got := months.FillGaps(GapFillZero); got.Len() != 4 || !got.Points[2].Time.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, Amsterdam)) {
+		t.Fatalf("FillGaps() = %v", got.Points)
+	}
+}
+
+func TestSeriesFillGapsUnaligned(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, Amsterdam)
+	// the points are off the 5 minute grid, 00:16 and 00:18 fall in the same interval
+	series := NewSeries("W", IntervalNone, []Point{
+		{start.Add(2 * time.Minute), 10},
+		{start.Add(16 * time.Minute), 30},
+		{start.Add(18 * time.Minute), 40},
+		{start.Add(21 * time.Minute), 0},
+	})
+
+	filled := series.FillGaps(GapFillLinear)
+	if want := []float64{10, 20, 30, 40, 0}; !reflect.DeepEqual(values(filled), want) {
+		t.Fatalf("FillGaps() = %v, want %v", values(filled), want)
+	}
+
+	for i, p := range filled.Points {
+		if want := start.Add(time.Duration(i) * FlowStep); !p.Time.Equal(want) {
+			t.Fatalf("point %d at %v, want %v", i, p.Time, want)
+		}
+	}
+
+	// days which do not start at midnight in Amsterdam are moved to midnight, across the DST change
+	days := NewSeries("kWh", IntervalDays, []Point{
+		{time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC), 1},
+		{time.Date(2024, 4, 2, 12, 0, 0, 0, time.UTC), 3},
+	})
+
+	filled = days.FillGaps(GapFillZero)
+	want := []time.Time{
+		time.Date(2024, 3, 31, 0, 0, 0, 0, Amsterdam),
+		time.Date(2024, 4, 1, 0, 0, 0, 0, Amsterdam),
+		time.Date(2024, 4, 2, 0, 0, 0, 0, Amsterdam),
+	}
+	if filled.Len() != len(want) {
+		t.Fatalf("FillGaps() = %v, want points at %v", filled.Points, want)
+	}
+
+	for i, p := range filled.Points {
+		if !p.Time.Equal(want[i]) {
+			t.Fatalf("point %d at %v, want %v", i, p.Time, want[i])
+		}
+	}
+}
+
+func TestSeriesAggregates(t *testing.T) {
+	series := NewSeries("W", IntervalHours, points(time.Unix(0, 0), time.Hour, 3, 1, 2))
+	minimum, _ := series.Min()
+	maximum, _ := series.Max()
+	if series.Sum() != 6 || series.Average() != 2 || minimum.Value != 1 || maximum.Value != 3 {
+		t.Fatalf("sum %v, average %v, min %v, max %v", series.Sum(), series.Average(), minimum, maximum)
+	}
+
+	if _, ok := (Series{}).Min(); ok || (Series{}).Average() != 0 {
+		t.Fatal("empty series should have no minimum and a zero average")
+	}
+}
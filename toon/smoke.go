@@ -0,0 +1,192 @@
+package toon
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smokeDetectorFields are the known JSON keys of a smoke detector, the schema is not documented
+// so alternative spellings are accepted in order of preference, the first key is used when marshalling
+var smokeDetectorFields = map[string][]string{
+	"devUUID":      {"devUUID", "devUuid", "uuid"},
+	"name":         {"name"},
+	"batteryLevel": {"batteryLevel", "battery"},
+	"connected":    {"connected", "isConnected"},
+	"lastAlarm":    {"lastAlarm", "lastAlarmTime"},
+}
+
+// SmokeDetector is a smoke detector connected to the Toon, fields which are not
+// known are kept in Extra so no information is lost
+type SmokeDetector struct {
+	DevUUID string
+	Name    string
+	// BatteryLevel in percent, -1 when unknown
+	BatteryLevel int
+	Connected    bool
+	// LastAlarm is the zero time when the detector never raised an alarm
+	LastAlarm time.Time
+	Extra     map[string]json.RawMessage
+}
+
+// UnmarshalJSON implements json.Unmarshaler, values are accepted as string or number. Only the first
+// spelling of a field which is present is used, other spellings are kept in Extra.
+func (d *SmokeDetector) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*d = SmokeDetector{BatteryLevel: -1}
+	for field, keys := range smokeDetectorFields {
+		for _, key := range keys {
+			raw, ok := fields[key]
+			if !ok {
+				continue
+			}
+
+			delete(fields, key)
+			value := rawString(raw)
+			switch field {
+			case "devUUID":
+				d.DevUUID = value
+			case "name":
+				d.Name = value
+			case "batteryLevel":
+				if level, err := strconv.ParseFloat(value, 64); err == nil {
+					d.BatteryLevel = int(level)
+				}
+			case "connected":
+				d.Connected = parseFlag(value)
+			case "lastAlarm":
+				d.LastAlarm = parseTimestamp(value)
+			}
+
+			break
+		}
+	}
+
+	if len(fields) > 0 {
+		d.Extra = fields
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the extra fields are included
+func (d SmokeDetector) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	for key, value := range d.Extra {
+		fields[key] = value
+	}
+
+	fields["devUUID"] = d.DevUUID
+	fields["name"] = d.Name
+	fields["batteryLevel"] = d.BatteryLevel
+	fields["connected"] = formatIntFlag(d.Connected)
+	if !d.LastAlarm.IsZero() {
+		fields["lastAlarm"] = d.LastAlarm.UnixMilli()
+	}
+
+	return json.Marshal(fields)
+}
+
+// rawString returns a JSON string, number or boolean as string
+func rawString(raw json.RawMessage) string {
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value
+	}
+
+	return strings.TrimSpace(string(raw))
+}
+
+// parseTimestamp parses a unix timestamp in seconds or milliseconds, the zero time is returned when empty or 0
+func parseTimestamp(value string) time.Time {
+	ts, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ts <= 0 {
+		return time.Time{}
+	}
+
+	if ts > 1e12 {
+		return time.UnixMilli(ts)
+	}
+
+	return time.Unix(ts, 0)
+}
+
+// SmokeDetectorEventType is the type of a SmokeDetectorEvent
+type SmokeDetectorEventType int
+
+// Smoke detector event types
+const (
+	SmokeDetectorLowBattery SmokeDetectorEventType = iota
+	SmokeDetectorDisconnected
+	SmokeDetectorAlarm
+)
+
+var smokeDetectorEventTypes = [...]string{
+	"low battery",
+	"disconnected",
+	"alarm",
+}
+
+// String() function will return the name of a smoke detector event type
+func (t SmokeDetectorEventType) String() string {
+	return smokeDetectorEventTypes[t]
+}
+
+// SmokeDetectorEvent is raised when the state of a smoke detector needs attention
+type SmokeDetectorEvent struct {
+	Type     SmokeDetectorEventType
+	Detector SmokeDetector
+}
+
+// DefaultLowBatteryLevel is the battery level in percent below which a low battery event is raised
+const DefaultLowBatteryLevel = 20
+
+// SmokeAlarmWindow is the age up to which the alarm of a detector without previous state is reported,
+// older alarms of the first snapshot or of a newly added detector were raised before monitoring started
+var SmokeAlarmWindow = 5 * time.Minute
+
+// SmokeDetectorEvents compares two successive status snapshots and returns the events for smoke detectors
+// which got a low battery, were disconnected or raised a new alarm. Supply nil as previous status for the first
+// snapshot, detectors with a low battery or which are disconnected are reported then. Alarms of detectors
+// without previous state are only reported when they are not older than SmokeAlarmWindow.
+func SmokeDetectorEvents(previous, current *Status, lowBatteryLevel int) []SmokeDetectorEvent {
+	before := map[string]SmokeDetector{}
+	if previous != nil {
+		for _, detector := range previous.SmokeDetectors.Devices {
+			before[detector.DevUUID] = detector
+		}
+	}
+
+	now := time.Now()
+	if current.ServerTime != 0 {
+		now = time.UnixMilli(current.ServerTime)
+	}
+
+	events := []SmokeDetectorEvent{}
+	for _, detector := range current.SmokeDetectors.Devices {
+		prev, seen := before[detector.DevUUID]
+		if !seen {
+			prev = SmokeDetector{BatteryLevel: -1, Connected: true, LastAlarm: now.Add(-SmokeAlarmWindow)}
+		}
+
+		low := func(d SmokeDetector) bool { return d.BatteryLevel >= 0 && d.BatteryLevel < lowBatteryLevel }
+		if low(detector) && !low(prev) {
+			events = append(events, SmokeDetectorEvent{Type: SmokeDetectorLowBattery, Detector: detector})
+		}
+
+		if !detector.Connected && prev.Connected {
+			events = append(events, SmokeDetectorEvent{Type: SmokeDetectorDisconnected, Detector: detector})
+		}
+
+		if detector.LastAlarm.After(prev.LastAlarm) {
+			events = append(events, SmokeDetectorEvent{Type: SmokeDetectorAlarm, Detector: detector})
+		}
+	}
+
+	return events
+}
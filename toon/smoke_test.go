@@ -0,0 +1,159 @@
+package toon
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSmokeDetectorUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    SmokeDetector
+		extra   []string
+	}{
+		{
+			name:    "known keys",
+			payload: `{"devUUID":"abc","name":"Hall","batteryLevel":"85","connected":1,"lastAlarm":1538460000}`,
+			want:    SmokeDetector{DevUUID: "abc", Name: "Hall", BatteryLevel: 85, Connected: true, LastAlarm: time.Unix(1538460000, 0)},
+		},
+		{
+			name:    "alternative spellings",
+			payload: `{"devUuid":"abc","battery":42.0,"isConnected":"0","lastAlarmTime":1538460000000}`,
+			want:    SmokeDetector{DevUUID: "abc", BatteryLevel: 42, LastAlarm: time.UnixMilli(1538460000000)},
+		},
+		{
+			name:    "first spelling wins, others are kept",
+			payload: `{"devUUID":"abc","uuid":"def","id":7,"battery":10,"batteryLevel":90}`,
+			want:    SmokeDetector{DevUUID: "abc", BatteryLevel: 90},
+			extra:   []string{"uuid", "id", "battery"},
+		},
+		{
+			name:    "unknown battery level",
+			payload: `{"devUUID":"abc","batteryLevel":"unknown"}`,
+			want:    SmokeDetector{DevUUID: "abc", BatteryLevel: -1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SmokeDetector{}
+			if err := json.Unmarshal([]byte(test.payload), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.DevUUID != test.want.DevUUID || got.Name != test.want.Name || got.BatteryLevel != test.want.BatteryLevel ||
+				got.Connected != test.want.Connected || !got.LastAlarm.Equal(test.want.LastAlarm) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+
+			if len(got.Extra) != len(test.extra) {
+				t.Fatalf("Extra = %v, want %v", got.Extra, test.extra)
+			}
+
+			for _, key := range test.extra {
+				if _, ok := got.Extra[key]; !ok {
+					t.Fatalf("Extra = %v, want %v", got.Extra, test.extra)
+				}
+			}
+
+			// marshalling keeps the extra fields
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			again := SmokeDetector{}
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatal(err)
+			}
+
+			if again.DevUUID != got.DevUUID || len(again.Extra) != len(got.Extra) {
+				t.Fatalf("round trip %+v, want %+v", again, got)
+			}
+		})
+	}
+}
+
+func TestSmokeDetectorEvents(t *testing.T) {
+	now := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	status := func(detectors ...SmokeDetector) *Status {
+		return &Status{ServerTime: now.UnixMilli(), SmokeDetectors: SmokeDetectors{Devices: detectors}}
+	}
+
+	healthy := SmokeDetector{DevUUID: "hall", BatteryLevel: 90, Connected: true, LastAlarm: now.Add(-24 * time.Hour)}
+	with := func(change func(d *SmokeDetector)) SmokeDetector {
+		detector := healthy
+		change(&detector)
+		return detector
+	}
+
+	tests := []struct {
+		name     string
+		previous *Status
+		current  *Status
+		want     []SmokeDetectorEventType
+	}{
+		{
+			name:    "first snapshot ignores old alarms",
+			current: status(healthy),
+			want:    []SmokeDetectorEventType{},
+		},
+		{
+			name:    "first snapshot reports recent alarms and problems",
+			current: status(with(func(d *SmokeDetector) { d.BatteryLevel, d.Connected, d.LastAlarm = 10, false, now.Add(-time.Minute) })),
+			want:    []SmokeDetectorEventType{SmokeDetectorLowBattery, SmokeDetectorDisconnected, SmokeDetectorAlarm},
+		},
+		{
+			name:     "unchanged",
+			previous: status(with(func(d *SmokeDetector) { d.BatteryLevel = 10 })),
+			current:  status(with(func(d *SmokeDetector) { d.BatteryLevel = 5 })),
+			want:     []SmokeDetectorEventType{},
+		},
+		{
+			name:     "new alarm",
+			previous: status(healthy),
+			current:  status(with(func(d *SmokeDetector) { d.LastAlarm = now.Add(-time.Hour) })),
+			want:     []SmokeDetectorEventType{SmokeDetectorAlarm},
+		},
+		{
+			name:     "disconnected",
+			previous: status(healthy),
+			current:  status(with(func(d *SmokeDetector) { d.Connected = false })),
+			want:     []SmokeDetectorEventType{SmokeDetectorDisconnected},
+		},
+		{
+			name:     "unknown battery level is not low",
+			previous: status(healthy),
+			current:  status(with(func(d *SmokeDetector) { d.BatteryLevel = -1 })),
+			want:     []SmokeDetectorEventType{},
+		},
+		{
+			name:     "added detector with an old alarm",
+			previous: status(healthy),
+			current:  status(healthy, with(func(d *SmokeDetector) { d.DevUUID = "attic" })),
+			want:     []SmokeDetectorEventType{},
+		},
+		{
+			name:     "added detector with a recent alarm",
+			previous: status(healthy),
+			current:  status(healthy, with(func(d *SmokeDetector) { d.DevUUID, d.LastAlarm = "attic", now })),
+			want:     []SmokeDetectorEventType{SmokeDetectorAlarm},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []SmokeDetectorEventType{}
+			for _, event := range SmokeDetectorEvents(test.previous, test.current, DefaultLowBatteryLevel) {
+				got = append(got, event.Type)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("SmokeDetectorEvents() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
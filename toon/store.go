@@ -0,0 +1,101 @@
+package toon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileStore keeps values by key in a JSON file, used by the file based stores which
+// pass their Path so it can still be changed after creating the store
+type fileStore[T any] struct {
+	mu sync.Mutex
+}
+
+func (s *fileStore[T]) save(path, key string, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.read(path)
+	if err != nil {
+		return err
+	}
+
+	values[key] = value
+	return s.write(path, values)
+}
+
+func (s *fileStore[T]) delete(path, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.read(path)
+	if err != nil {
+		return err
+	}
+
+	delete(values, key)
+	return s.write(path, values)
+}
+
+func (s *fileStore[T]) list(path string) ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]T, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+
+	return list, nil
+}
+
+func (s *fileStore[T]) read(path string) (map[string]T, error) {
+	values := map[string]T{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+// write writes the values to a temporary file first so the store is never left half written
+func (s *fileStore[T]) write(path string, values map[string]T) error {
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,42 @@
+package toon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileBoostStore(t *testing.T) {
+	store := NewFileBoostStore(filepath.Join(t.TempDir(), "boosts.json"))
+	boost := Boost{AgreementID: "1", Setpoint: 21, Until: time.Now().Add(time.Hour).Round(0)}
+	if err := store.Save(boost); err != nil {
+		t.Fatal(err)
+	}
+
+	boosts, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(boosts) != 1 || boosts[0].AgreementID != "1" || !boosts[0].Until.Equal(boost.Until) {
+		t.Fatalf("List() = %+v, want %+v", boosts, boost)
+	}
+
+	// the store follows its Path
+	store.Path = filepath.Join(t.TempDir(), "other.json")
+	if boosts, err = store.List(); err != nil || len(boosts) != 0 {
+		t.Fatalf("List() = %+v, %v, want empty", boosts, err)
+	}
+
+	if err := store.Save(boost); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if boosts, err = store.List(); err != nil || len(boosts) != 0 {
+		t.Fatalf("List() = %+v, %v, want empty", boosts, err)
+	}
+}
@@ -0,0 +1,255 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+// ErrInvalidTimeRange is returned when the end of a time range is not after the start or
+// the range is longer than allowed for the interval
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
+// Amsterdam is the timezone of the Toon displays, used by the time range presets. When the
+// timezone database is not available a fixed CET offset is used, import time/tzdata to embed it.
+var Amsterdam = loadAmsterdam()
+
+func loadAmsterdam() *time.Location {
+	loc, err := time.LoadLocation("Europe/Amsterdam")
+	if err != nil {
+		return time.FixedZone("CET", 60*60)
+	}
+
+	return loc
+}
+
+// maxSpans is the longest time range which can be requested at once per interval, IntervalNone
+// is used for the 5 minute flow data. The Toon API does not document a maximum, these limits are
+// chosen by the SDK to keep responses small: a week of 5 minute flow data is 2016 points and the
+// other intervals return at most 744 points. Use FetchFlowDataChunked to request longer ranges.
+var maxSpans = map[Interval]time.Duration{
+	IntervalNone:   7 * 24 * time.Hour,
+	IntervalHours:  31 * 24 * time.Hour,
+	IntervalDays:   366 * 24 * time.Hour,
+	IntervalWeeks:  5 * 366 * 24 * time.Hour,
+	IntervalMonths: 10 * 366 * 24 * time.Hour,
+}
+
+// MaxSpan returns the longest time range which can be requested at once for the interval,
+// 0 means there is no maximum. IntervalNone is the 5 minute flow data.
+func (i Interval) MaxSpan() time.Duration {
+	return maxSpans[i]
+}
+
+// TimeRange is the period for which consumption or production data is requested, the zero
+// TimeRange requests the default period of the API which is the last 24 hours
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// IsZero returns true for the zero TimeRange
+func (r TimeRange) IsZero() bool {
+	return r.Start.IsZero() && r.End.IsZero()
+}
+
+// Duration returns the length of the time range
+func (r TimeRange) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Validate checks that the interval is known, the end is after the start and the range is
+// not longer than the MaxSpan of the interval, the zero TimeRange is valid for any known interval
+func (r TimeRange) Validate(interval Interval) error {
+	if !interval.valid() {
+		return fmt.Errorf("%w: unknown interval %d", ErrInvalidTimeRange, interval)
+	}
+
+	if r.IsZero() {
+		return nil
+	}
+
+	if r.Start.IsZero() || r.End.IsZero() || !r.End.After(r.Start) {
+		return fmt.Errorf("%w: end %v should be after start %v", ErrInvalidTimeRange, r.End, r.Start)
+	}
+
+	if max := interval.MaxSpan(); max > 0 && r.Duration() > max {
+		return fmt.Errorf("%w: %v is longer than the maximum of %v for interval %v", ErrInvalidTimeRange, r.Duration(), max, interval)
+	}
+
+	return nil
+}
+
+// millis returns the start and end as unix timestamps in milliseconds, 0 when not set
+func (r TimeRange) millis() (int64, int64) {
+	var start, end int64
+	if !r.Start.IsZero() {
+		start = r.Start.UnixMilli()
+	}
+
+	if !r.End.IsZero() {
+		end = r.End.UnixMilli()
+	}
+
+	return start, end
+}
+
+// Today returns the time range of the current day in Amsterdam
+func Today() TimeRange {
+	return today(time.Now())
+}
+
+// Yesterday returns the time range of the previous day in Amsterdam
+func Yesterday() TimeRange {
+	return yesterday(time.Now())
+}
+
+// ThisWeek returns the time range of the current week in Amsterdam, weeks start on Monday
+func ThisWeek() TimeRange {
+	return thisWeek(time.Now())
+}
+
+// LastMonth returns the time range of the previous calendar month in Amsterdam
+func LastMonth() TimeRange {
+	return lastMonth(time.Now())
+}
+
+// today returns the day of now in Amsterdam, days are calendar days so they are 23 or 25 hours
+// long when daylight saving time starts or ends
+func today(now time.Time) TimeRange {
+	now = now.In(Amsterdam)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, Amsterdam)
+	return TimeRange{Start: start, End: start.AddDate(0, 0, 1)}
+}
+
+func yesterday(now time.Time) TimeRange {
+	r := today(now)
+	return TimeRange{Start: r.Start.AddDate(0, 0, -1), End: r.Start}
+}
+
+func thisWeek(now time.Time) TimeRange {
+	r := today(now)
+	start := r.Start.AddDate(0, 0, -(int(r.Start.Weekday())+6)%7)
+	return TimeRange{Start: start, End: start.AddDate(0, 0, 7)}
+}
+
+func lastMonth(now time.Time) TimeRange {
+	now = now.In(Amsterdam)
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, Amsterdam)
+	return TimeRange{Start: end.AddDate(0, -1, 0), End: end}
+}
+
+// graphInterval returns the interval used by the API for graph data, IntervalNone means hourly data there
+func graphInterval(interval Interval) Interval {
+	if interval == IntervalNone {
+		return IntervalHours
+	}
+
+	return interval
+}
+
+func invalidTimeRange(err error) *ErrorResponse {
+	return newErrorResponse(err, "Invalid time range")
+}
+
+// GetGasFlowDataRange is GetGasFlowData for a TimeRange
+func GetGasFlowDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(IntervalNone); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetGasFlowData(auth, agreementID, start, end)
+}
+
+// GetElectricityGraphDataRange is GetElectricityGraphData for a TimeRange
+func GetElectricityGraphDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, interval Interval) (*ElectricityGraphData, *ErrorResponse) {
+	if err := r.Validate(graphInterval(interval)); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetElectricityGraphData(auth, agreementID, start, end, interval)
+}
+
+// GetDistrictHeatGraphDataRange is GetDistrictHeatGraphData for a TimeRange
+func GetDistrictHeatGraphDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, interval Interval) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(graphInterval(interval)); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetDistrictHeatGraphData(auth, agreementID, start, end, interval)
+}
+
+// GetElectricityFlowDataRange is GetElectricityFlowData for a TimeRange
+func GetElectricityFlowDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(IntervalNone); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetElectricityFlowData(auth, agreementID, start, end)
+}
+
+// GetGasGraphDataRange is GetGasGraphData for a TimeRange
+func GetGasGraphDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, interval Interval) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(graphInterval(interval)); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetGasGraphData(auth, agreementID, start, end, interval)
+}
+
+// GetElectricityProductionFlowDataRange is GetElectricityProductionFlowData for a TimeRange
+func GetElectricityProductionFlowDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(IntervalNone); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetElectricityProductionFlowData(auth, agreementID, start, end)
+}
+
+// GetElectricityProductionGraphDataRange is GetElectricityProductionGraphData for a TimeRange
+func GetElectricityProductionGraphDataRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, interval Interval) (*ElectricityGraphData, *ErrorResponse) {
+	if err := r.Validate(graphInterval(interval)); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetElectricityProductionGraphData(auth, agreementID, start, end, interval)
+}
+
+// GetElectricityProductionAndDeliveryRange is GetElectricityProductionAndDelivery for a TimeRange
+func GetElectricityProductionAndDeliveryRange(auth *auth.ToonAuthenticator, agreementID string, r TimeRange, interval Interval) (*ProductionAndDelivery, *ErrorResponse) {
+	if err := r.Validate(graphInterval(interval)); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetElectricityProductionAndDelivery(auth, agreementID, start, end, interval)
+}
+
+// GetDeviceGraphDataRange is GetDeviceGraphData for a TimeRange
+func GetDeviceGraphDataRange(auth *auth.ToonAuthenticator, agreementID string, device DeviceConfig, r TimeRange, interval Interval) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(graphInterval(interval)); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetDeviceGraphData(auth, agreementID, device, start, end, interval)
+}
+
+// GetDeviceFlowDataRange is GetDeviceFlowData for a TimeRange
+func GetDeviceFlowDataRange(auth *auth.ToonAuthenticator, agreementID string, device DeviceConfig, r TimeRange) (*FlowData, *ErrorResponse) {
+	if err := r.Validate(IntervalNone); err != nil {
+		return nil, invalidTimeRange(err)
+	}
+
+	start, end := r.millis()
+	return GetDeviceFlowData(auth, agreementID, device, start, end)
+}
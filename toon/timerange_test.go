@@ -0,0 +1,94 @@
+package toon
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestTimeRangeValidate(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, Amsterdam)
+	tests := []struct {
+		name     string
+		r        TimeRange
+		interval Interval
+		valid    bool
+	}{
+		{"zero range", TimeRange{}, IntervalHours, true},
+		{"zero range unknown interval", TimeRange{}, 0, false},
+		{"unknown interval", TimeRange{Start: start, End: start.Add(time.Hour)}, IntervalYears + 1, false},
+		{"end before start", TimeRange{Start: start, End: start.Add(-time.Hour)}, IntervalHours, false},
+		{"only start", TimeRange{Start: start}, IntervalHours, false},
+		{"max span", TimeRange{Start: start, End: start.Add(IntervalNone.MaxSpan())}, IntervalNone, true},
+		{"longer than max span", TimeRange{Start: start, End: start.Add(IntervalNone.MaxSpan() + time.Minute)}, IntervalNone, false},
+		{"no max span", TimeRange{Start: start, End: start.AddDate(50, 0, 0)}, IntervalYears, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.r.Validate(test.interval)
+			if test.valid && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+
+			if !test.valid && !errors.Is(err, ErrInvalidTimeRange) {
+				t.Fatalf("Validate() = %v, want %v", err, ErrInvalidTimeRange)
+			}
+		})
+	}
+}
+
+func TestGraphDataRangeUnknownInterval(t *testing.T) {
+	// the interval is validated before any request is made, the zero range included
+	_, err := GetElectricityGraphDataRange(nil, "1", TimeRange{}, 0)
+	if err == nil || !errors.Is(err.Err, ErrInvalidTimeRange) {
+		t.Fatalf("GetElectricityGraphDataRange() = %v, want %v", err, ErrInvalidTimeRange)
+	}
+}
+
+func TestTimeRangePresets(t *testing.T) {
+	if Amsterdam.String() != "Europe/Amsterdam" {
+		t.Skip("timezone database not available")
+	}
+
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, Amsterdam)
+	}
+
+	tests := []struct {
+		name     string
+		preset   func(now time.Time) TimeRange
+		now      time.Time
+		start    time.Time
+		end      time.Time
+		duration time.Duration
+	}{
+		{name: "today", preset: today, now: at(2024, 7, 10, 15), start: at(2024, 7, 10, 0), end: at(2024, 7, 11, 0), duration: 24 * time.Hour},
+		{name: "today in utc", preset: today, now: time.Date(2024, 7, 9, 23, 30, 0, 0, time.UTC), start: at(2024, 7, 10, 0), end: at(2024, 7, 11, 0), duration: 24 * time.Hour},
+		{name: "today dst start", preset: today, now: at(2024, 3, 31, 12), start: at(2024, 3, 31, 0), end: at(2024, 4, 1, 0), duration: 23 * time.Hour},
+		{name: "today dst end", preset: today, now: at(2024, 10, 27, 12), start: at(2024, 10, 27, 0), end: at(2024, 10, 28, 0), duration: 25 * time.Hour},
+		{name: "yesterday", preset: yesterday, now: at(2024, 7, 10, 0), start: at(2024, 7, 9, 0), end: at(2024, 7, 10, 0), duration: 24 * time.Hour},
+		{name: "yesterday dst end", preset: yesterday, now: at(2024, 10, 28, 8), start: at(2024, 10, 27, 0), end: at(2024, 10, 28, 0), duration: 25 * time.Hour},
+		{name: "this week", preset: thisWeek, now: at(2024, 7, 10, 15), start: at(2024, 7, 8, 0), end: at(2024, 7, 15, 0), duration: 7 * 24 * time.Hour},
+		{name: "this week on sunday", preset: thisWeek, now: at(2024, 7, 14, 23), start: at(2024, 7, 8, 0), end: at(2024, 7, 15, 0), duration: 7 * 24 * time.Hour},
+		{name: "this week dst start", preset: thisWeek, now: at(2024, 3, 31, 12), start: at(2024, 3, 25, 0), end: at(2024, 4, 1, 0), duration: 7*24*time.Hour - time.Hour},
+		{name: "last month", preset: lastMonth, now: at(2024, 7, 10, 15), start: at(2024, 6, 1, 0), end: at(2024, 7, 1, 0), duration: 30 * 24 * time.Hour},
+		{name: "last month in january", preset: lastMonth, now: at(2024, 1, 1, 0), start: at(2023, 12, 1, 0), end: at(2024, 1, 1, 0), duration: 31 * 24 * time.Hour},
+		{name: "last month dst start", preset: lastMonth, now: at(2024, 4, 15, 12), start: at(2024, 3, 1, 0), end: at(2024, 4, 1, 0), duration: 31*24*time.Hour - time.Hour},
+		{name: "last month dst end", preset: lastMonth, now: at(2024, 11, 2, 12), start: at(2024, 10, 1, 0), end: at(2024, 11, 1, 0), duration: 31*24*time.Hour + time.Hour},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := test.preset(test.now)
+			if !r.Start.Equal(test.start) || !r.End.Equal(test.end) {
+				t.Fatalf("got %v - %v, want %v - %v", r.Start, r.End, test.start, test.end)
+			}
+
+			if r.Duration() != test.duration {
+				t.Fatalf("Duration() = %v, want %v", r.Duration(), test.duration)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package toon
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/tebben/toon-go-sdk/auth"
 )
@@ -12,16 +14,29 @@ var (
 	statusEndpoint                = "/status"
 	gasFlowsEndpoint              = "/consumption/gas/flows"
 	electricityGraphDataEndpoint  = "/consumption/electricity/data"
+	enecoGraphDataEndpoint        = "/consumption/electricity/data/eneco"
 	districtHeatGraphDataEndpoint = "/consumption/districtheat/data"
 	electricityFlowDataEndpoint   = "/consumption/electricity/flows"
 	gasGraphDataEndpoint          = "/consumption/gas/data"
+	productionFlowDataEndpoint    = "/production/electricity/flows"
+	productionGraphDataEndpoint   = "/production/electricity/data"
+	productionDeliveryEndpoint    = "/production/electricity/delivery"
+	thermostatEndpoint            = "/thermostat"
+	thermostatStatesEndpoint      = "/thermostat/states"
+	thermostatProgramsEndpoint    = "/thermostat/programs"
+	devicesEndpoint               = "/devices"
+	deviceEndpoint                = "/devices/{devUUID}"
+	deviceGraphDataEndpoint       = "/devices/{graphUUID}/data"
+	deviceFlowDataEndpoint        = "/devices/{graphUUID}/flows"
+	webhooksEndpoint              = "/webhooks"
+	webhookEndpoint               = "/webhooks/{applicationId}"
 )
 
 // GetAgreements returns the agreementID(s) that are associated with the utility customer.
 // The agreementID is used in subsequent calls to access the data of one particular Toon.
 func GetAgreements(auth *auth.ToonAuthenticator) (*Agreements, *ErrorResponse) {
 	agreements := &Agreements{}
-	err := get(constructEndpointURI(agreementEndpoint, nil, ""), auth, agreements, false)
+	err := get(agreementEndpoint, nil, "", auth, agreements, false)
 	return agreements, err
 }
 
@@ -29,7 +44,15 @@ func GetAgreements(auth *auth.ToonAuthenticator) (*Agreements, *ErrorResponse) {
 // thermostat information and thermostat programs aswell as connected devices.
 func GetStatus(auth *auth.ToonAuthenticator, agreementID string) (*Status, *ErrorResponse) {
 	status := &Status{}
-	err := get(constructEndpointURI(statusEndpoint, nil, agreementID), auth, status, false)
+	err := get(statusEndpoint, nil, agreementID, auth, status, false)
+	return status, err
+}
+
+// PollStatus is GetStatus for background pollers, it can not use the BackgroundReserve of the rate
+// limiter so interactive requests keep their budget and waiting for budget stops when ctx is done
+func PollStatus(ctx context.Context, auth *auth.ToonAuthenticator, agreementID string) (*Status, *ErrorResponse) {
+	status := &Status{}
+	err := request(requestOptions{ctx: ctx, background: true}, http.MethodGet, statusEndpoint, nil, agreementID, auth, nil, status, false)
 	return status, err
 }
 
@@ -41,7 +64,7 @@ func GetStatus(auth *auth.ToonAuthenticator, agreementID string) (*Status, *Erro
 // start and end = unix timestamp in milliseconds, supply 0 for start and end when not using
 func GetGasFlowData(auth *auth.ToonAuthenticator, agreementID string, start, end int64) (*FlowData, *ErrorResponse) {
 	flowData := &FlowData{}
-	err := get(constructEndpointURI(gasFlowsEndpoint, constructTimeParams(start, end, IntervalNone), agreementID), auth, flowData, false)
+	err := get(gasFlowsEndpoint, constructTimeParams(start, end, IntervalNone), agreementID, auth, flowData, false)
 	return flowData, err
 }
 
@@ -51,7 +74,15 @@ func GetGasFlowData(auth *auth.ToonAuthenticator, agreementID string, start, end
 // 24 hours and the default interval is hourly. Supply 0 for start and end when not using
 func GetElectricityGraphData(auth *auth.ToonAuthenticator, agreementID string, start, end int64, interval Interval) (*ElectricityGraphData, *ErrorResponse) {
 	graphData := &ElectricityGraphData{}
-	err := get(constructEndpointURI(electricityGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID), auth, graphData, false)
+	err := get(electricityGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID, auth, graphData, false)
+	return graphData, err
+}
+
+// GetElectricityGraphDataEneco returns the electricity consumption using the Eneco specific variant of
+// GetElectricityGraphData. GetElectricityGraphData routes to this variant automatically for Eneco customers.
+func GetElectricityGraphDataEneco(auth *auth.ToonAuthenticator, agreementID string, start, end int64, interval Interval) (*ElectricityGraphData, *ErrorResponse) {
+	graphData := &ElectricityGraphData{}
+	err := get(enecoGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID, auth, graphData, false)
 	return graphData, err
 }
 
@@ -61,7 +92,7 @@ func GetElectricityGraphData(auth *auth.ToonAuthenticator, agreementID string, s
 // and the default interval is hourly. Supply 0 for start and end when not using
 func GetDistrictHeatGraphData(auth *auth.ToonAuthenticator, agreementID string, start, end int64, interval Interval) (*FlowData, *ErrorResponse) {
 	flowData := &FlowData{}
-	err := get(constructEndpointURI(districtHeatGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID), auth, flowData, false)
+	err := get(districtHeatGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID, auth, flowData, false)
 	return flowData, err
 }
 
@@ -71,7 +102,7 @@ func GetDistrictHeatGraphData(auth *auth.ToonAuthenticator, agreementID string,
 // the default time period will be used, which is the last 24 hours.
 func GetElectricityFlowData(auth *auth.ToonAuthenticator, agreementID string, start, end int64) (*FlowData, *ErrorResponse) {
 	flowData := &FlowData{}
-	err := get(constructEndpointURI(electricityFlowDataEndpoint, constructTimeParams(start, end, IntervalNone), agreementID), auth, flowData, false)
+	err := get(electricityFlowDataEndpoint, constructTimeParams(start, end, IntervalNone), agreementID, auth, flowData, false)
 	return flowData, err
 }
 
@@ -80,14 +111,286 @@ func GetElectricityFlowData(auth *auth.ToonAuthenticator, agreementID string, st
 // the default values will be used. The default time period is the last 24 hours and the default interval is hourly.
 func GetGasGraphData(auth *auth.ToonAuthenticator, agreementID string, start, end int64, interval Interval) (*FlowData, *ErrorResponse) {
 	flowData := &FlowData{}
-	err := get(constructEndpointURI(gasGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID), auth, flowData, false)
+	err := get(gasGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID, auth, flowData, false)
 	return flowData, err
 }
 
-// ToDo: Consumption
-// unsubscribePushEvent
-// getWebhooks
-// subscribeToPushEvent
+// Production
+
+// GetElectricityProductionFlowData returns the solar electricity production for a given time period in 5 minute
+// intervals. The data is given for the time period between the given from- and toTime parameters. If no parameters
+// are specified, the default time period will be used, which is the last 24 hours. Only available when
+// Agreement.IsToonSolar is set.
+func GetElectricityProductionFlowData(auth *auth.ToonAuthenticator, agreementID string, start, end int64) (*FlowData, *ErrorResponse) {
+	flowData := &FlowData{}
+	err := get(productionFlowDataEndpoint, constructTimeParams(start, end, IntervalNone), agreementID, auth, flowData, false)
+	return flowData, err
+}
+
+// GetElectricityProductionGraphData returns the solar electricity production for a given time period. The data is
+// given in the interval you specify and for the time period between the given from- and toTime parameters.
+// If no parameters are specified, the default values will be used. The default time period is the last 24 hours
+// and the default interval is hourly. Only available when Agreement.IsToonSolar is set.
+func GetElectricityProductionGraphData(auth *auth.ToonAuthenticator, agreementID string, start, end int64, interval Interval) (*ElectricityGraphData, *ErrorResponse) {
+	graphData := &ElectricityGraphData{}
+	err := get(productionGraphDataEndpoint, constructTimeParams(start, end, interval), agreementID, auth, graphData, false)
+	return graphData, err
+}
+
+// GetElectricityProductionAndDelivery returns the electricity consumed from the grid, delivered to the grid and
+// produced for a given time period, split by peak and off-peak tariff. The data is given in the interval you specify
+// and for the time period between the given from- and toTime parameters, with the same defaults as
+// GetElectricityGraphData. Use Balance on the result to compute a net metering balance.
+func GetElectricityProductionAndDelivery(auth *auth.ToonAuthenticator, agreementID string, start, end int64, interval Interval) (*ProductionAndDelivery, *ErrorResponse) {
+	data := &ProductionAndDelivery{}
+	err := get(productionDeliveryEndpoint, constructTimeParams(start, end, interval), agreementID, auth, data, false)
+	return data, err
+}
+
+// GetWebhooks returns the webhooks registered for push events of an agreement.
+func GetWebhooks(auth *auth.ToonAuthenticator, agreementID string) (*Webhooks, *ErrorResponse) {
+	webhooks := &Webhooks{}
+	err := get(webhooksEndpoint, nil, agreementID, auth, webhooks, false)
+	return webhooks, err
+}
+
+// SubscribeToPushEvent registers a webhook which receives the subscribed push events of an agreement,
+// the callback URL should be an absolute https URL.
+func SubscribeToPushEvent(auth *auth.ToonAuthenticator, agreementID string, webhook Webhook) *ErrorResponse {
+	if err := webhook.Validate(); err != nil {
+		return newErrorResponse(err, "Invalid webhook")
+	}
+
+	return post(webhooksEndpoint, nil, agreementID, auth, webhook, nil)
+}
+
+// UnsubscribePushEvent removes the webhook of an application from an agreement.
+func UnsubscribePushEvent(auth *auth.ToonAuthenticator, agreementID, applicationID string) *ErrorResponse {
+	return del(webhookEndpoint, map[string]string{"applicationId": applicationID}, agreementID, auth, nil)
+}
+
+// Thermostat
+
+// GetThermostatStates returns the thermostat states, these are the presets Comfort, Home, Sleep, Away
+// and Holiday with their temperature.
+func GetThermostatStates(auth *auth.ToonAuthenticator, agreementID string) (*ThermostatStates, *ErrorResponse) {
+	states := &ThermostatStates{}
+	err := get(thermostatStatesEndpoint, nil, agreementID, auth, states, false)
+	return states, err
+}
+
+// SetThermostatState sets the temperature in degrees Celsius of a preset, the temperature should be between
+// MinTemperature and MaxTemperature. The updated thermostat states are returned.
+func SetThermostatState(auth *auth.ToonAuthenticator, agreementID string, preset Preset, celsius float64) (*ThermostatStates, *ErrorResponse) {
+	if err := validateTemperature(celsius); err != nil {
+		return nil, newErrorResponse(err, "Invalid temperature")
+	}
+
+	states, err := GetThermostatStates(auth, agreementID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for i, state := range states.States {
+		if state.Preset() == preset {
+			states.States[i].TempValue = fromCelsius(celsius)
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, newErrorResponse(fmt.Errorf("preset %v not found in thermostat states", preset), "Invalid preset")
+	}
+
+	err = put(thermostatStatesEndpoint, nil, agreementID, auth, states, nil)
+	return states, err
+}
+
+// SetActivePreset activates a preset, with OverrideTemporary the weekly program takes over again at the next
+// program change, with OverridePermanent the weekly program is switched off.
+func SetActivePreset(auth *auth.ToonAuthenticator, agreementID string, preset Preset, mode OverrideMode) *ErrorResponse {
+	if preset == PresetNone || preset < PresetComfort || preset > PresetHoliday {
+		return newErrorResponse(fmt.Errorf("preset %v can not be activated", int(preset)), "Invalid preset")
+	}
+
+	activeState := int(preset)
+	programState := mode.programState()
+	return put(thermostatEndpoint, nil, agreementID, auth, thermostatUpdate{ActiveState: &activeState, ProgramState: &programState}, nil)
+}
+
+// GetThermostatInfo returns the current state of the thermostat, such as the setpoint, displayed temperature,
+// program state and boiler information.
+func GetThermostatInfo(auth *auth.ToonAuthenticator, agreementID string) (*ThermostatInfo, *ErrorResponse) {
+	info := &ThermostatInfo{}
+	err := get(thermostatEndpoint, nil, agreementID, auth, info, false)
+	return info, err
+}
+
+// GetCurrentTemperature returns the room temperature and current setpoint in degrees Celsius.
+func GetCurrentTemperature(auth *auth.ToonAuthenticator, agreementID string) (*CurrentTemperature, *ErrorResponse) {
+	info, err := GetThermostatInfo(auth, agreementID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CurrentTemperature{
+		Room:     toCelsius(info.CurrentDisplayTemp),
+		Setpoint: toCelsius(info.CurrentSetpoint),
+		Override: info.IsOverride(),
+	}, nil
+}
+
+// UpdateCurrentTemperature sets a manual setpoint in degrees Celsius, the setpoint should be between MinTemperature
+// and MaxTemperature. With OverrideTemporary the weekly program takes over again at the next program change,
+// with OverridePermanent the weekly program is switched off.
+func UpdateCurrentTemperature(auth *auth.ToonAuthenticator, agreementID string, celsius float64, mode OverrideMode) *ErrorResponse {
+	if err := validateTemperature(celsius); err != nil {
+		return newErrorResponse(err, "Invalid temperature")
+	}
+
+	setpoint := fromCelsius(celsius)
+	programState := mode.programState()
+	activeState := int(PresetNone)
+	return put(thermostatEndpoint, nil, agreementID, auth, thermostatUpdate{CurrentSetpoint: &setpoint, ProgramState: &programState, ActiveState: &activeState}, nil)
+}
+
+// ResumeProgram switches the weekly program back on, ending any temporary or permanent override.
+func ResumeProgram(auth *auth.ToonAuthenticator, agreementID string) *ErrorResponse {
+	programState := ProgramOn
+	return put(thermostatEndpoint, nil, agreementID, auth, thermostatUpdate{ProgramState: &programState}, nil)
+}
+
+// GetThermostatProgram returns the weekly program of the thermostat, program entries which span
+// midnight are split into a block per day.
+func GetThermostatProgram(auth *auth.ToonAuthenticator, agreementID string) (*WeekProgram, *ErrorResponse) {
+	programs := &thermostatPrograms{}
+	if err := get(thermostatProgramsEndpoint, nil, agreementID, auth, programs, false); err != nil {
+		return nil, err
+	}
+
+	program, err := programs.weekProgram()
+	if err != nil {
+		return nil, newErrorResponse(err, "Unexpected response")
+	}
+
+	return program, nil
+}
+
+// UpdateThermostatProgram replaces the weekly program of the thermostat, the program is validated
+// before it is send and rejected when blocks overlap, leave gaps or exceed MaxProgramBlocksPerDay.
+func UpdateThermostatProgram(auth *auth.ToonAuthenticator, agreementID string, program WeekProgram) *ErrorResponse {
+	if err := program.Validate(); err != nil {
+		return newErrorResponse(err, "Invalid program")
+	}
+
+	return put(thermostatProgramsEndpoint, nil, agreementID, auth, newThermostatPrograms(program), nil)
+}
+
+// Devices
+
+// GetDevices returns the configuration of all smart plugs and lights connected to the Toon.
+func GetDevices(auth *auth.ToonAuthenticator, agreementID string) (*DeviceConfigs, *ErrorResponse) {
+	devices := &DeviceConfigs{}
+	err := get(devicesEndpoint, nil, agreementID, auth, devices, false)
+	return devices, err
+}
+
+// UpdateDevices updates the configuration of multiple devices at once, the resulting configuration
+// of all devices is returned.
+func UpdateDevices(auth *auth.ToonAuthenticator, agreementID string, devices DeviceConfigs) (*DeviceConfigs, *ErrorResponse) {
+	if err := put(devicesEndpoint, nil, agreementID, auth, devices, nil); err != nil {
+		return nil, err
+	}
+
+	return GetDevices(auth, agreementID)
+}
+
+// GetDevice returns the configuration of a device by its DevUUID.
+func GetDevice(auth *auth.ToonAuthenticator, agreementID, devUUID string) (*DeviceConfig, *ErrorResponse) {
+	device := &DeviceConfig{}
+	err := get(deviceEndpoint, map[string]string{"devUUID": devUUID}, agreementID, auth, device, false)
+	return device, err
+}
+
+// UpdateDevice updates the configuration of a device, the resulting configuration is returned.
+func UpdateDevice(auth *auth.ToonAuthenticator, agreementID string, device DeviceConfig) (*DeviceConfig, *ErrorResponse) {
+	if err := put(deviceEndpoint, map[string]string{"devUUID": device.DevUUID}, agreementID, auth, device, nil); err != nil {
+		return nil, err
+	}
+
+	return GetDevice(auth, agreementID, device.DevUUID)
+}
+
+// SwitchDevice switches a device on or off, the resulting configuration is returned.
+func SwitchDevice(auth *auth.ToonAuthenticator, agreementID, devUUID string, on bool) (*DeviceConfig, *ErrorResponse) {
+	return modifyDevice(auth, agreementID, devUUID, func(device *DeviceConfig) {
+		device.CurrentState = formatFlag(on)
+	})
+}
+
+// SetDeviceSwitchLocked locks or unlocks switching a device, the resulting configuration is returned.
+func SetDeviceSwitchLocked(auth *auth.ToonAuthenticator, agreementID, devUUID string, locked bool) (*DeviceConfig, *ErrorResponse) {
+	return modifyDevice(auth, agreementID, devUUID, func(device *DeviceConfig) {
+		device.SwitchLocked = formatFlag(locked)
+	})
+}
+
+// SetDeviceInSwitchAll adds or removes a device from the switch all function, the resulting configuration is returned.
+func SetDeviceInSwitchAll(auth *auth.ToonAuthenticator, agreementID, devUUID string, in bool) (*DeviceConfig, *ErrorResponse) {
+	return modifyDevice(auth, agreementID, devUUID, func(device *DeviceConfig) {
+		device.InSwitchAll = formatIntFlag(in)
+	})
+}
+
+// SetDeviceInSwitchSchedule enables or disables the schedule of a device, the resulting configuration is returned.
+func SetDeviceInSwitchSchedule(auth *auth.ToonAuthenticator, agreementID, devUUID string, in bool) (*DeviceConfig, *ErrorResponse) {
+	return modifyDevice(auth, agreementID, devUUID, func(device *DeviceConfig) {
+		device.InSwitchSchedule = formatIntFlag(in)
+	})
+}
+
+// GetDeviceGraphData returns the electricity consumption of a smart plug for a given time period, using the
+// QuantityGraphUUID of the device. The data is given in the interval you specify and for the time period between
+// the given from- and toTime parameters, with the same defaults as GetElectricityGraphData.
+func GetDeviceGraphData(auth *auth.ToonAuthenticator, agreementID string, device DeviceConfig, start, end int64, interval Interval) (*FlowData, *ErrorResponse) {
+	flowData := &FlowData{}
+	if len(device.QuantityGraphUUID) == 0 {
+		return flowData, newErrorResponse(fmt.Errorf("device %v has no quantity graph", device.DevUUID), "Unsupported device")
+	}
+
+	params := constructTimeParams(start, end, interval)
+	params["graphUUID"] = device.QuantityGraphUUID
+
+	err := get(deviceGraphDataEndpoint, params, agreementID, auth, flowData, false)
+	return flowData, err
+}
+
+// GetDeviceFlowData returns the electricity consumption of a smart plug for a given time period in 5 minute
+// intervals, using the FlowGraphUUID of the device. The default time period is the last 24 hours.
+func GetDeviceFlowData(auth *auth.ToonAuthenticator, agreementID string, device DeviceConfig, start, end int64) (*FlowData, *ErrorResponse) {
+	flowData := &FlowData{}
+	if len(device.FlowGraphUUID) == 0 {
+		return flowData, newErrorResponse(fmt.Errorf("device %v has no flow graph", device.DevUUID), "Unsupported device")
+	}
+
+	params := constructTimeParams(start, end, IntervalNone)
+	params["graphUUID"] = device.FlowGraphUUID
+
+	err := get(deviceFlowDataEndpoint, params, agreementID, auth, flowData, false)
+	return flowData, err
+}
+
+// modifyDevice reads the configuration of a device, applies the modification and writes it back
+func modifyDevice(auth *auth.ToonAuthenticator, agreementID, devUUID string, modify func(device *DeviceConfig)) (*DeviceConfig, *ErrorResponse) {
+	device, err := GetDevice(auth, agreementID, devUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	modify(device)
+	return UpdateDevice(auth, agreementID, *device)
+}
 
 func constructTimeParams(start, end int64, interval Interval) map[string]string {
 	params := map[string]string{}
@@ -96,7 +399,7 @@ func constructTimeParams(start, end int64, interval Interval) map[string]string
 	}
 
 	if end != 0 {
-		params["toTime"] = fmt.Sprintf("%v", start)
+		params["toTime"] = fmt.Sprintf("%v", end)
 	}
 
 	if interval.String() != "" && interval != IntervalNone {
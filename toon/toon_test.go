@@ -0,0 +1,202 @@
+package toon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tebben/toon-go-sdk/auth"
+)
+
+func TestDeviceEndpoints(t *testing.T) {
+	const device = `{"devUUID":"plug/1","name":"Lamp","currentState":"0","switchLocked":"0","inSwitchAll":0,"inSwitchSchedule":1}`
+	tests := []struct {
+		name string
+		call func(authenticator *auth.ToonAuthenticator) (*DeviceConfig, *ErrorResponse)
+		// requests are the method and path of the requests made, the put is checked against payload
+		requests []string
+		payload  string
+	}{
+		{
+			name: "get",
+			call: func(authenticator *auth.ToonAuthenticator) (*DeviceConfig, *ErrorResponse) {
+				return GetDevice(authenticator, "1", "plug/1")
+			},
+			requests: []string{"GET /toon/v3/1/devices/plug%2F1"},
+		},
+		{
+			name: "switch",
+			call: func(authenticator *auth.ToonAuthenticator) (*DeviceConfig, *ErrorResponse) {
+				return SwitchDevice(authenticator, "1", "plug/1", true)
+			},
+			requests: []string{"GET /toon/v3/1/devices/plug%2F1", "PUT /toon/v3/1/devices/plug%2F1", "GET /toon/v3/1/devices/plug%2F1"},
+			payload:  `"currentState":"1"`,
+		},
+		{
+			name: "switch locked",
+			call: func(authenticator *auth.ToonAuthenticator) (*DeviceConfig, *ErrorResponse) {
+				return SetDeviceSwitchLocked(authenticator, "1", "plug/1", true)
+			},
+			requests: []string{"GET /toon/v3/1/devices/plug%2F1", "PUT /toon/v3/1/devices/plug%2F1", "GET /toon/v3/1/devices/plug%2F1"},
+			payload:  `"switchLocked":"1"`,
+		},
+		{
+			name: "in switch all",
+			call: func(authenticator *auth.ToonAuthenticator) (*DeviceConfig, *ErrorResponse) {
+				return SetDeviceInSwitchAll(authenticator, "1", "plug/1", true)
+			},
+			requests: []string{"GET /toon/v3/1/devices/plug%2F1", "PUT /toon/v3/1/devices/plug%2F1", "GET /toon/v3/1/devices/plug%2F1"},
+			payload:  `"inSwitchAll":1`,
+		},
+		{
+			name: "in switch schedule",
+			call: func(authenticator *auth.ToonAuthenticator) (*DeviceConfig, *ErrorResponse) {
+				return SetDeviceInSwitchSchedule(authenticator, "1", "plug/1", false)
+			},
+			requests: []string{"GET /toon/v3/1/devices/plug%2F1", "PUT /toon/v3/1/devices/plug%2F1", "GET /toon/v3/1/devices/plug%2F1"},
+			payload:  `"inSwitchSchedule":0`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := recordRequests(t, map[string]string{"/toon/v3/1/devices/plug%2F1": device})
+			got, err := test.call(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0))
+			if err != nil || got.DevUUID != "plug/1" {
+				t.Fatalf("got %+v, %v, want device plug/1", got, err)
+			}
+
+			methods := []string{}
+			for _, request := range *requests {
+				methods = append(methods, request.Method+" "+request.Path)
+				if request.Method == "PUT" && !strings.Contains(request.Body, test.payload) {
+					t.Fatalf("payload %s does not contain %s", request.Body, test.payload)
+				}
+			}
+
+			if !reflect.DeepEqual(methods, test.requests) {
+				t.Fatalf("requests %v, want %v", methods, test.requests)
+			}
+		})
+	}
+}
+
+func TestGetDevices(t *testing.T) {
+	requests := recordRequests(t, map[string]string{"/toon/v3/1/devices": `[{"devUUID":"a","currentState":"1","inSwitchAll":1},{"devUUID":"b"}]`})
+
+	devices, err := GetDevices(auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0), "1")
+	if err != nil || len(*devices) != 2 {
+		t.Fatalf("GetDevices() = %+v, %v, want 2 devices", devices, err)
+	}
+
+	if device, ok := devices.Get("a"); !ok || !device.IsOn() || !device.IsInSwitchAll() {
+		t.Fatalf("Get(a) = %+v, %v, want a device which is on and in switch all", device, ok)
+	}
+
+	if len(*requests) != 1 || (*requests)[0].Method != "GET" {
+		t.Fatalf("requests %+v, want one GET", *requests)
+	}
+}
+
+func TestDeviceGraphAndFlowData(t *testing.T) {
+	device := DeviceConfig{DevUUID: "plug", FlowGraphUUID: "flow-1", QuantityGraphUUID: "quantity-1"}
+	tests := []struct {
+		name  string
+		call  func(authenticator *auth.ToonAuthenticator, device DeviceConfig) (*FlowData, *ErrorResponse)
+		path  string
+		query string
+	}{
+		{
+			name: "graph data",
+			call: func(authenticator *auth.ToonAuthenticator, device DeviceConfig) (*FlowData, *ErrorResponse) {
+				return GetDeviceGraphData(authenticator, "1", device, 1000, 2000, IntervalDays)
+			},
+			path:  "/toon/v3/1/devices/quantity-1/data",
+			query: "fromTime=1000&interval=days&toTime=2000",
+		},
+		{
+			name: "flow data",
+			call: func(authenticator *auth.ToonAuthenticator, device DeviceConfig) (*FlowData, *ErrorResponse) {
+				return GetDeviceFlowData(authenticator, "1", device, 1000, 2000)
+			},
+			path:  "/toon/v3/1/devices/flow-1/flows",
+			query: "fromTime=1000&toTime=2000",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := recordRequests(t, nil)
+			authenticator := auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0)
+			if _, err := test.call(authenticator, device); err != nil {
+				t.Fatalf("got %v", err)
+			}
+
+			if len(*requests) != 1 {
+				t.Fatalf("requests %+v, want one", *requests)
+			}
+
+			request := (*requests)[0]
+			if request.Method != "GET" || request.Path != test.path || request.Query.Encode() != test.query {
+				t.Fatalf("request %v %v?%v, want GET %v?%v", request.Method, request.Path, request.Query.Encode(), test.path, test.query)
+			}
+
+			// a device without graph is rejected without a request, like other getters data is not nil
+			data, err := test.call(authenticator, DeviceConfig{DevUUID: "plug"})
+			if err == nil || data == nil || len(*requests) != 1 {
+				t.Fatalf("got %v, %v after %d requests, want empty data and an error without request", data, err, len(*requests))
+			}
+		})
+	}
+}
+
+func TestProductionData(t *testing.T) {
+	requests := recordRequests(t, map[string]string{
+		"/toon/v3/1/production/electricity/flows": `{"hours":[{"timestamp":1000,"unit":"W","value":1250}]}`,
+		"/toon/v3/1/production/electricity/data":  `{"days":[{"timestamp":1000,"unit":"Wh","peak":3000,"offPeak":1500}]}`,
+	})
+	authenticator := auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0)
+
+	flows, err := GetElectricityProductionFlowData(authenticator, "1", 1000, 2000)
+	if err != nil || len(flows.Hours) != 1 || flows.Hours[0].Value != 1250 {
+		t.Fatalf("GetElectricityProductionFlowData() = %+v, %v", flows, err)
+	}
+
+	graph, err := GetElectricityProductionGraphData(authenticator, "1", 1000, 2000, IntervalDays)
+	if err != nil || len(graph.Days) != 1 || graph.Days[0].Peak != 3000 || graph.Days[0].OffPeak != 1500 {
+		t.Fatalf("GetElectricityProductionGraphData() = %+v, %v", graph, err)
+	}
+
+	want := []string{
+		"GET /toon/v3/1/production/electricity/flows?fromTime=1000&toTime=2000",
+		"GET /toon/v3/1/production/electricity/data?fromTime=1000&interval=days&toTime=2000",
+	}
+
+	got := []string{}
+	for _, request := range *requests {
+		got = append(got, request.Method+" "+request.Path+"?"+request.Query.Encode())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("requests %v, want %v", got, want)
+	}
+}
+
+func TestSetActivePreset(t *testing.T) {
+	requests := recordRequests(t, nil)
+	authenticator := auth.NewToonAuthenticator("id", "secret", "eneco", "", "", "", 0)
+
+	for _, preset := range []Preset{PresetNone, Preset(len(PresetEnum))} {
+		if err := SetActivePreset(authenticator, "1", preset, OverrideTemporary); err == nil {
+			t.Fatalf("SetActivePreset(%d) succeeded, want an invalid preset", int(preset))
+		}
+	}
+
+	if err := SetActivePreset(authenticator, "1", PresetAway, OverrideTemporary); err != nil {
+		t.Fatalf("SetActivePreset() = %v", err)
+	}
+
+	if len(*requests) != 1 || (*requests)[0].Method != "PUT" || !strings.Contains((*requests)[0].Body, `"activeState":3`) {
+		t.Fatalf("requests %+v, want one PUT activating away", *requests)
+	}
+}
@@ -0,0 +1,247 @@
+package toon
+
+import (
+	"context"
+	"time"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// WatchEventType is the type of a WatchEvent
+type WatchEventType int
+
+// Watch event types
+const (
+	// WatchSetpointChanged is emitted when the thermostat setpoint changed, Previous and Current are in degrees Celsius
+	WatchSetpointChanged WatchEventType = iota
+	// WatchBurnerOn is emitted when the boiler burner started heating the house or hot water
+	WatchBurnerOn
+	// WatchBurnerOff is emitted when the boiler burner became idle
+	WatchBurnerOff
+	// WatchPlugToggled is emitted when a smart plug was switched, On holds the new state
+	WatchPlugToggled
+	// WatchPowerThresholdCrossed is emitted when the power usage crossed WatchConfig.PowerThreshold,
+	// Previous and Current are in Watt and On is true when the usage went above the threshold
+	WatchPowerThresholdCrossed
+	// WatchDisplayOffline is emitted when the display did not update the server for WatchConfig.DisplayOfflineAfter
+	WatchDisplayOffline
+	// WatchDisplayOnline is emitted when the display updates the server again after being offline
+	WatchDisplayOnline
+	// WatchError is emitted when polling the status failed, the watch continues with a backoff
+	WatchError
+)
+
+var watchEventTypes = [...]string{
+	"setpoint changed",
+	"burner on",
+	"burner off",
+	"plug toggled",
+	"power threshold crossed",
+	"display offline",
+	"display online",
+	"error",
+}
+
+// String() function will return the name of a watch event type
+func (t WatchEventType) String() string {
+	return watchEventTypes[t]
+}
+
+// WatchEvent is a change detected between two successive status snapshots
+type WatchEvent struct {
+	Type        WatchEventType
+	AgreementID string
+	// Status is the snapshot in which the change was detected, nil for WatchError
+	Status   *Status
+	Previous float64
+	Current  float64
+	// DevUUID and Name identify the plug of a WatchPlugToggled event
+	DevUUID string
+	Name    string
+	On      bool
+	Err     *ErrorResponse
+}
+
+// WatchConfig contains the settings of a watch
+type WatchConfig struct {
+	// Interval is the time between polls while the display is updating the server
+	Interval time.Duration
+	// MaxInterval is the maximum time between polls, the interval is doubled up to MaxInterval
+	// while LastUpdateFromDisplay does not move or polling fails, default 8 times Interval
+	MaxInterval time.Duration
+	// PowerThreshold in Watt, 0 disables WatchPowerThresholdCrossed events
+	PowerThreshold int
+	// DisplayOfflineAfter is the time since LastUpdateFromDisplay after which the display is considered offline, default 15 minutes
+	DisplayOfflineAfter time.Duration
+	// Buffer is the size of the event channel
+	Buffer int
+}
+
+// Watch polls the status of an agreement every interval and emits the changes on the returned channel,
+// see WatchWithConfig. The channel is closed when the context is done.
+func Watch(ctx context.Context, auth *auth.ToonAuthenticator, agreementID string, interval time.Duration) <-chan WatchEvent {
+	return WatchWithConfig(ctx, auth, agreementID, WatchConfig{Interval: interval})
+}
+
+// WatchWithConfig polls the status of an agreement and emits the changes between successive snapshots on the
+// returned channel. Polling backs off while LastUpdateFromDisplay does not move, since the status will not have
+// changed, and a poll is skipped when the rate limiter asks background pollers to yield. Polls use PollStatus
+// so they never use the BackgroundReserve of the rate limiter. The first snapshot only
+// reports a display which is already offline. The channel is closed when the context is done.
+func WatchWithConfig(ctx context.Context, auth *auth.ToonAuthenticator, agreementID string, config WatchConfig) <-chan WatchEvent {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+
+	if config.MaxInterval < config.Interval {
+		config.MaxInterval = 8 * config.Interval
+	}
+
+	if config.DisplayOfflineAfter <= 0 {
+		config.DisplayOfflineAfter = 15 * time.Minute
+	}
+
+	events := make(chan WatchEvent, config.Buffer)
+	go watch(ctx, auth, agreementID, config, events)
+	return events
+}
+
+func watch(ctx context.Context, auth *auth.ToonAuthenticator, agreementID string, config WatchConfig, events chan<- WatchEvent) {
+	defer close(events)
+
+	var previous *Status
+	delay := config.Interval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if rl := rateLimiter.Load(); rl != nil && rl.Yield(CallRead) {
+			telemetry.Logger().Debug("toon watch poll skipped", "agreement_id", agreementID, "reason", "rate limit reserve")
+			delay = watchBackoff(delay, config.MaxInterval)
+			timer.Reset(delay)
+			continue
+		}
+
+		status, err := PollStatus(ctx, auth, agreementID)
+		if err != nil {
+			delay = watchBackoff(delay, config.MaxInterval)
+			if !sendWatchEvent(ctx, events, WatchEvent{Type: WatchError, AgreementID: agreementID, Err: err}) {
+				return
+			}
+
+			timer.Reset(delay)
+			continue
+		}
+
+		delay = watchDelay(delay, previous, status, config)
+		for _, event := range watchEvents(agreementID, previous, status, config) {
+			if !sendWatchEvent(ctx, events, event) {
+				return
+			}
+		}
+
+		previous = status
+		timer.Reset(delay)
+	}
+}
+
+// watchDelay returns the delay until the next poll, polling backs off while LastUpdateFromDisplay does not move
+func watchDelay(delay time.Duration, previous, current *Status, config WatchConfig) time.Duration {
+	if previous != nil && current.LastUpdateFromDisplay == previous.LastUpdateFromDisplay {
+		return watchBackoff(delay, config.MaxInterval)
+	}
+
+	return config.Interval
+}
+
+func watchBackoff(delay, max time.Duration) time.Duration {
+	if delay*2 > max {
+		return max
+	}
+
+	return delay * 2
+}
+
+func sendWatchEvent(ctx context.Context, events chan<- WatchEvent, event WatchEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// watchEvents returns the events between two successive snapshots, previous is nil for the first snapshot
+func watchEvents(agreementID string, previous, current *Status, config WatchConfig) []WatchEvent {
+	events := []WatchEvent{}
+	newEvent := func(t WatchEventType) WatchEvent {
+		return WatchEvent{Type: t, AgreementID: agreementID, Status: current}
+	}
+
+	offline := DisplayOffline(current, config.DisplayOfflineAfter)
+	if previous == nil {
+		if offline {
+			events = append(events, newEvent(WatchDisplayOffline))
+		}
+
+		return events
+	}
+
+	if wasOffline := DisplayOffline(previous, config.DisplayOfflineAfter); offline != wasOffline {
+		if offline {
+			events = append(events, newEvent(WatchDisplayOffline))
+		} else {
+			events = append(events, newEvent(WatchDisplayOnline))
+		}
+	}
+
+	before, after := previous.ThermostatInfo, current.ThermostatInfo
+	if before.CurrentSetpoint != after.CurrentSetpoint {
+		event := newEvent(WatchSetpointChanged)
+		event.Previous, event.Current = before.SetpointCelsius(), after.SetpointCelsius()
+		events = append(events, event)
+	}
+
+	if before.Burner() != BurnerUnknown && after.Burner() != BurnerUnknown {
+		wasOn, on := before.Burner() != BurnerIdle, after.Burner() != BurnerIdle
+		if on && !wasOn {
+			events = append(events, newEvent(WatchBurnerOn))
+		} else if !on && wasOn {
+			events = append(events, newEvent(WatchBurnerOff))
+		}
+	}
+
+	plugs := map[string]DeviceStatus{}
+	for _, plug := range previous.DeviceStatusInfo.Status {
+		plugs[plug.DevUUID] = plug
+	}
+
+	for _, plug := range current.DeviceStatusInfo.Status {
+		prev, ok := plugs[plug.DevUUID]
+		if !ok || prev.CurrentState == plug.CurrentState {
+			continue
+		}
+
+		event := newEvent(WatchPlugToggled)
+		event.DevUUID, event.Name, event.On = plug.DevUUID, plug.Name, plug.CurrentState == 1
+		events = append(events, event)
+	}
+
+	if threshold := config.PowerThreshold; threshold > 0 {
+		was, is := previous.PowerUsage.Value, current.PowerUsage.Value
+		if (was < threshold) != (is < threshold) {
+			event := newEvent(WatchPowerThresholdCrossed)
+			event.Previous, event.Current, event.On = float64(was), float64(is), is >= threshold
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,147 @@
+package toon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatchEvents(t *testing.T) {
+	now := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	status := func(change func(s *Status)) *Status {
+		s := &Status{
+			ServerTime:            now.UnixMilli(),
+			LastUpdateFromDisplay: now.Add(-time.Minute).UnixMilli(),
+			ThermostatInfo:        ThermostatInfo{CurrentSetpoint: 2000, BurnerInfo: "0"},
+			DeviceStatusInfo:      DeviceStatusInfo{Status: []DeviceStatus{{DevUUID: "plug", Name: "Lamp", CurrentState: 0}}},
+			PowerUsage:            PowerUsage{Value: 400},
+		}
+		if change != nil {
+			change(s)
+		}
+
+		return s
+	}
+	offline := func(s *Status) { s.LastUpdateFromDisplay = now.Add(-time.Hour).UnixMilli() }
+
+	tests := []struct {
+		name     string
+		previous *Status
+		current  *Status
+		want     []WatchEventType
+		// values are the Previous, Current and On fields of the first event
+		values []interface{}
+	}{
+		{name: "first snapshot", current: status(nil), want: []WatchEventType{}},
+		{name: "first snapshot offline", current: status(offline), want: []WatchEventType{WatchDisplayOffline}},
+		{name: "unchanged", previous: status(nil), current: status(nil), want: []WatchEventType{}},
+		{
+			name:     "setpoint",
+			previous: status(nil),
+			current:  status(func(s *Status) { s.ThermostatInfo.CurrentSetpoint = 2150 }),
+			want:     []WatchEventType{WatchSetpointChanged},
+			values:   []interface{}{20.0, 21.5, false},
+		},
+		{
+			name:     "burner on",
+			previous: status(nil),
+			current:  status(func(s *Status) { s.ThermostatInfo.BurnerInfo = "2" }),
+			want:     []WatchEventType{WatchBurnerOn},
+		},
+		{
+			name:     "burner off",
+			previous: status(func(s *Status) { s.ThermostatInfo.BurnerInfo = "1" }),
+			current:  status(nil),
+			want:     []WatchEventType{WatchBurnerOff},
+		},
+		{
+			name:     "unknown burner state",
+			previous: status(nil),
+			current:  status(func(s *Status) { s.ThermostatInfo.BurnerInfo = "" }),
+			want:     []WatchEventType{},
+		},
+		{
+			name:     "plug toggled",
+			previous: status(nil),
+			current:  status(func(s *Status) { s.DeviceStatusInfo.Status[0].CurrentState = 1 }),
+			want:     []WatchEventType{WatchPlugToggled},
+			values:   []interface{}{0.0, 0.0, true},
+		},
+		{
+			name:     "new plug",
+			previous: status(func(s *Status) { s.DeviceStatusInfo.Status = nil }),
+			current:  status(nil),
+			want:     []WatchEventType{},
+		},
+		{
+			name:     "power above threshold",
+			previous: status(nil),
+			current:  status(func(s *Status) { s.PowerUsage.Value = 3000 }),
+			want:     []WatchEventType{WatchPowerThresholdCrossed},
+			values:   []interface{}{400.0, 3000.0, true},
+		},
+		{
+			name:     "power below threshold",
+			previous: status(func(s *Status) { s.PowerUsage.Value = 2500 }),
+			current:  status(nil),
+			want:     []WatchEventType{WatchPowerThresholdCrossed},
+			values:   []interface{}{2500.0, 400.0, false},
+		},
+		{
+			name:     "power stays above threshold",
+			previous: status(func(s *Status) { s.PowerUsage.Value = 2500 }),
+			current:  status(func(s *Status) { s.PowerUsage.Value = 3000 }),
+			want:     []WatchEventType{},
+		},
+		{name: "offline", previous: status(nil), current: status(offline), want: []WatchEventType{WatchDisplayOffline}},
+		{name: "online", previous: status(offline), current: status(nil), want: []WatchEventType{WatchDisplayOnline}},
+	}
+
+	config := WatchConfig{PowerThreshold: 2000, DisplayOfflineAfter: 15 * time.Minute}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := watchEvents("1", test.previous, test.current, config)
+			got := []WatchEventType{}
+			for _, event := range events {
+				got = append(got, event.Type)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("watchEvents() = %v, want %v", got, test.want)
+			}
+
+			if test.values != nil {
+				event := events[0]
+				if values := []interface{}{event.Previous, event.Current, event.On}; !reflect.DeepEqual(values, test.values) {
+					t.Fatalf("event values %v, want %v", values, test.values)
+				}
+			}
+		})
+	}
+}
+
+func TestWatchDelay(t *testing.T) {
+	config := WatchConfig{Interval: time.Minute, MaxInterval: 4 * time.Minute}
+	status := func(lastUpdate int64) *Status { return &Status{LastUpdateFromDisplay: lastUpdate} }
+
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		previous *Status
+		current  *Status
+		want     time.Duration
+	}{
+		{name: "first snapshot", delay: time.Minute, current: status(1), want: time.Minute},
+		{name: "display did not update", delay: time.Minute, previous: status(1), current: status(1), want: 2 * time.Minute},
+		{name: "back off up to the max interval", delay: 3 * time.Minute, previous: status(1), current: status(1), want: 4 * time.Minute},
+		{name: "display updated", delay: 4 * time.Minute, previous: status(1), current: status(2), want: time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := watchDelay(test.delay, test.previous, test.current, config); got != test.want {
+				t.Fatalf("watchDelay() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/tebben/toon-go-sdk/auth"
+	"github.com/tebben/toon-go-sdk/telemetry"
+)
+
+// ErrInvalidWebhook is returned when a webhook has an invalid callback URL or event type
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// PushEventType is a type of event Toon can push to a webhook
+type PushEventType string
+
+// Push event types
+const (
+	PushEventThermostat      PushEventType = "Thermostat"
+	PushEventPowerUsage      PushEventType = "PowerUsage"
+	PushEventGasUsage        PushEventType = "GasUsage"
+	PushEventBoilerErrorInfo PushEventType = "BoilerErrorInfo"
+	PushEventSmartPlug       PushEventType = "SmartPlug"
+)
+
+// PushEventTypeEnum contains all push event types
+var PushEventTypeEnum = []PushEventType{PushEventThermostat, PushEventPowerUsage, PushEventGasUsage, PushEventBoilerErrorInfo, PushEventSmartPlug}
+
+// Webhooks contains the webhooks registered for an agreement
+type Webhooks []Webhook
+
+// Webhook is a callback URL which receives the subscribed push events of an agreement
+type Webhook struct {
+	ApplicationID     string          `json:"applicationId"`
+	CallbackURL       string          `json:"callbackUrl"`
+	SubscribedActions []PushEventType `json:"subscribedActions"`
+}
+
+// Validate checks the application id, the callback URL, which should be an absolute https URL,
+// and the subscribed event types
+func (w Webhook) Validate() error {
+	if len(w.ApplicationID) == 0 {
+		return fmt.Errorf("%w: application id is empty", ErrInvalidWebhook)
+	}
+
+	u, err := url.Parse(w.CallbackURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	if u.Scheme != "https" || len(u.Hostname()) == 0 {
+		return fmt.Errorf("%w: callback url %q should be an absolute https url", ErrInvalidWebhook, w.CallbackURL)
+	}
+
+	if u.User != nil || len(u.Fragment) != 0 {
+		return fmt.Errorf("%w: callback url %q should not contain user info or a fragment", ErrInvalidWebhook, w.CallbackURL)
+	}
+
+	if len(w.SubscribedActions) == 0 {
+		return fmt.Errorf("%w: no subscribed actions", ErrInvalidWebhook)
+	}
+
+	for _, action := range w.SubscribedActions {
+		if !isPushEventType(action) {
+			return fmt.Errorf("%w: unknown push event type %q", ErrInvalidWebhook, action)
+		}
+	}
+
+	return nil
+}
+
+// Equal returns true when both webhooks have the same application id, callback URL and subscribed actions,
+// the order of the subscribed actions is ignored
+func (w Webhook) Equal(other Webhook) bool {
+	if w.ApplicationID != other.ApplicationID || w.CallbackURL != other.CallbackURL || len(w.SubscribedActions) != len(other.SubscribedActions) {
+		return false
+	}
+
+	a, b := sortedActions(w.SubscribedActions), sortedActions(other.SubscribedActions)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortedActions(actions []PushEventType) []PushEventType {
+	sorted := append([]PushEventType(nil), actions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func isPushEventType(t PushEventType) bool {
+	for _, known := range PushEventTypeEnum {
+		if known == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookReconcileResult contains the changes made by ReconcileWebhooks
+type WebhookReconcileResult struct {
+	Subscribed   []Webhook `json:"subscribed"`
+	Updated      []Webhook `json:"updated"`
+	Unsubscribed []Webhook `json:"unsubscribed"`
+	Unchanged    []Webhook `json:"unchanged"`
+}
+
+// ReconcileWebhooks makes the registered webhooks of an agreement match the desired webhooks, webhooks are matched
+// by application id. Missing webhooks are subscribed, changed webhooks are resubscribed and webhooks which are not
+// desired are unsubscribed. All desired webhooks are validated before anything is changed. When resubscribing a
+// changed webhook fails the registered webhook is restored, so the application keeps receiving events.
+func ReconcileWebhooks(auth *auth.ToonAuthenticator, agreementID string, desired Webhooks) (*WebhookReconcileResult, *ErrorResponse) {
+	wanted := map[string]Webhook{}
+	for _, webhook := range desired {
+		if err := webhook.Validate(); err != nil {
+			return nil, newErrorResponse(err, "Invalid webhook")
+		}
+
+		if _, ok := wanted[webhook.ApplicationID]; ok {
+			return nil, newErrorResponse(fmt.Errorf("%w: duplicate application id %q", ErrInvalidWebhook, webhook.ApplicationID), "Invalid webhook")
+		}
+
+		wanted[webhook.ApplicationID] = webhook
+	}
+
+	registered, err := GetWebhooks(auth, agreementID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WebhookReconcileResult{Subscribed: []Webhook{}, Updated: []Webhook{}, Unsubscribed: []Webhook{}, Unchanged: []Webhook{}}
+	current := map[string]Webhook{}
+	for _, webhook := range *registered {
+		current[webhook.ApplicationID] = webhook
+		want, ok := wanted[webhook.ApplicationID]
+		if ok && want.Equal(webhook) {
+			result.Unchanged = append(result.Unchanged, webhook)
+			continue
+		}
+
+		if err := UnsubscribePushEvent(auth, agreementID, webhook.ApplicationID); err != nil {
+			return result, err
+		}
+
+		if !ok {
+			result.Unsubscribed = append(result.Unsubscribed, webhook)
+			continue
+		}
+
+		if err := SubscribeToPushEvent(auth, agreementID, want); err != nil {
+			// the registered webhook is posted as is, it was accepted before so it is not validated again
+			if restoreErr := post(webhooksEndpoint, nil, agreementID, auth, webhook, nil); restoreErr != nil {
+				telemetry.Logger().Warn("unable to restore toon webhook", "application_id", webhook.ApplicationID, "error", restoreErr)
+				result.Unsubscribed = append(result.Unsubscribed, webhook)
+			}
+
+			return result, err
+		}
+
+		result.Updated = append(result.Updated, want)
+	}
+
+	for _, webhook := range desired {
+		if _, ok := current[webhook.ApplicationID]; ok {
+			continue
+		}
+
+		if err := SubscribeToPushEvent(auth, agreementID, webhook); err != nil {
+			return result, err
+		}
+
+		result.Subscribed = append(result.Subscribed, webhook)
+	}
+
+	return result, nil
+}